@@ -0,0 +1,137 @@
+package star
+
+import (
+	"strconv"
+
+	"github.com/google/go-github/v32/github"
+)
+
+// RepoAttr is repository entity attribute.
+type RepoAttr string
+
+const (
+	// DescriptionAttr is repository description
+	DescriptionAttr RepoAttr = "description"
+	// StarsAttr is the number of stargazers
+	StarsAttr RepoAttr = "stars"
+	// ForksAttr is the number of forks
+	ForksAttr RepoAttr = "forks"
+	// WatchersAttr is the number of watchers
+	WatchersAttr RepoAttr = "watchers"
+	// OpenIssuesAttr is the number of open issues
+	OpenIssuesAttr RepoAttr = "open_issues"
+	// LicenseAttr is SPDX ID of repository license
+	LicenseAttr RepoAttr = "license"
+	// DefaultBranchAttr is repository default branch
+	DefaultBranchAttr RepoAttr = "default_branch"
+	// CreatedAtAttr is repository creation time.
+	// NOTE: created_at is the creation time of the stored entity.
+	CreatedAtAttr RepoAttr = "repo_created_at"
+	// UpdatedAtAttr is repository update time
+	UpdatedAtAttr RepoAttr = "updated_at"
+	// PushedAtAttr is the time of the last push
+	PushedAtAttr RepoAttr = "pushed_at"
+	// ArchivedAttr is true if repository is archived
+	ArchivedAttr RepoAttr = "archived"
+	// ForkAttr is true if repository is a fork
+	ForkAttr RepoAttr = "fork"
+	// TemplateAttr is true if repository is a template
+	TemplateAttr RepoAttr = "template"
+	// HomepageAttr is repository homepage
+	HomepageAttr RepoAttr = "homepage"
+)
+
+// AllRepoAttrs returns all repository attributes.
+func AllRepoAttrs() []RepoAttr {
+	return []RepoAttr{
+		DescriptionAttr,
+		StarsAttr,
+		ForksAttr,
+		WatchersAttr,
+		OpenIssuesAttr,
+		LicenseAttr,
+		DefaultBranchAttr,
+		CreatedAtAttr,
+		UpdatedAtAttr,
+		PushedAtAttr,
+		ArchivedAttr,
+		ForkAttr,
+		TemplateAttr,
+		HomepageAttr,
+	}
+}
+
+// value returns the value of attribute a of repo r.
+// It returns false if the attribute is not set.
+func (a RepoAttr) value(r *github.Repository) (string, bool) {
+	switch a {
+	case DescriptionAttr:
+		return stringValue(r.Description)
+	case StarsAttr:
+		return intValue(r.StargazersCount)
+	case ForksAttr:
+		return intValue(r.ForksCount)
+	case WatchersAttr:
+		return intValue(r.WatchersCount)
+	case OpenIssuesAttr:
+		return intValue(r.OpenIssuesCount)
+	case LicenseAttr:
+		if r.License == nil {
+			return "", false
+		}
+		if id := r.License.GetSPDXID(); id != "" {
+			return id, true
+		}
+		return stringValue(r.License.Key)
+	case DefaultBranchAttr:
+		return stringValue(r.DefaultBranch)
+	case CreatedAtAttr:
+		return timeValue(r.CreatedAt)
+	case UpdatedAtAttr:
+		return timeValue(r.UpdatedAt)
+	case PushedAtAttr:
+		return timeValue(r.PushedAt)
+	case ArchivedAttr:
+		return boolValue(r.Archived)
+	case ForkAttr:
+		return boolValue(r.Fork)
+	case TemplateAttr:
+		return boolValue(r.IsTemplate)
+	case HomepageAttr:
+		return stringValue(r.Homepage)
+	}
+
+	return "", false
+}
+
+func stringValue(s *string) (string, bool) {
+	if s == nil || *s == "" {
+		return "", false
+	}
+
+	return *s, true
+}
+
+func intValue(i *int) (string, bool) {
+	if i == nil {
+		return "", false
+	}
+
+	return strconv.Itoa(*i), true
+}
+
+func boolValue(b *bool) (string, bool) {
+	if b == nil {
+		return "", false
+	}
+
+	return strconv.FormatBool(*b), true
+}
+
+func timeValue(t *github.Timestamp) (string, bool) {
+	if t == nil || t.IsZero() {
+		return "", false
+	}
+
+	return t.Format(dateTime), true
+}
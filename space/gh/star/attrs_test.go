@@ -0,0 +1,94 @@
+package star
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v32/github"
+)
+
+func TestRepoAttrValue(t *testing.T) {
+	created := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	repo := &github.Repository{
+		Description:     github.String("graph scraper"),
+		StargazersCount: github.Int(42),
+		License:         &github.License{Key: github.String("apache-2.0"), SPDXID: github.String("Apache-2.0")},
+		CreatedAt:       &github.Timestamp{Time: created},
+		Archived:        github.Bool(false),
+		Homepage:        github.String(""),
+	}
+
+	testCases := []struct {
+		attr RepoAttr
+		val  string
+		ok   bool
+	}{
+		{DescriptionAttr, "graph scraper", true},
+		{StarsAttr, "42", true},
+		{ForksAttr, "", false},
+		{LicenseAttr, "Apache-2.0", true},
+		{CreatedAtAttr, created.Format(dateTime), true},
+		{PushedAtAttr, "", false},
+		{ArchivedAttr, "false", true},
+		{HomepageAttr, "", false},
+		{RepoAttr("unknown"), "", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(string(tc.attr), func(t *testing.T) {
+			val, ok := tc.attr.value(repo)
+			if val != tc.val || ok != tc.ok {
+				t.Errorf("expected: %q %v, got: %q %v", tc.val, tc.ok, val, ok)
+			}
+		})
+	}
+}
+
+func TestMapRepoAttrs(t *testing.T) {
+	star := newTestStar(0, "owner", time.Now())
+	star.Repo.Stars = 42
+
+	ts := newTestServer([]testStar{star})
+	defer ts.Close()
+
+	s, err := NewScraper(ts.client(), RepoAttrs(StarsAttr, DescriptionAttr))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	top, err := testMap(context.Background(), s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ents, err := top.Entities(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var found bool
+
+	for _, e := range ents {
+		if e.Resource().Name() != repoRes {
+			continue
+		}
+
+		found = true
+
+		if stars := e.Attrs().Get(string(StarsAttr)); stars != "42" {
+			t.Errorf("expected stars: %s, got: %s", "42", stars)
+		}
+
+		for _, k := range e.Attrs().Keys() {
+			if k == string(DescriptionAttr) {
+				t.Errorf("unexpected empty description attribute")
+			}
+		}
+	}
+
+	if !found {
+		t.Fatal("repo entity not found")
+	}
+}
@@ -0,0 +1,318 @@
+package star
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultCacheSize is default maximum size of HTTP cache in bytes.
+	DefaultCacheSize = 100 << 20
+	// cacheExt is HTTP cache file extension
+	cacheExt = ".http"
+)
+
+// CacheStats are HTTP cache statistics.
+type CacheStats struct {
+	// Hits is the number of responses served from cache
+	Hits int
+	// Misses is the number of responses fetched from GitHub API
+	Misses int
+	// Evictions is the number of responses evicted from cache
+	Evictions int
+	// Entries is the number of cached responses
+	Entries int
+	// Size is the size of cached responses in bytes
+	Size int64
+}
+
+// CacheOptions configure Transport.
+type CacheOptions struct {
+	// MaxSize is the maximum size of cached responses in bytes
+	MaxSize int64
+	// Base is the underlying HTTP transport
+	Base http.RoundTripper
+}
+
+// CacheOption is Transport option.
+type CacheOption func(*CacheOptions)
+
+// MaxCacheSize configures the maximum size of cached responses in bytes.
+func MaxCacheSize(n int64) CacheOption {
+	return func(o *CacheOptions) {
+		o.MaxSize = n
+	}
+}
+
+// BaseTransport configures the underlying HTTP transport, e.g. an authenticating one.
+func BaseTransport(rt http.RoundTripper) CacheOption {
+	return func(o *CacheOptions) {
+		o.Base = rt
+	}
+}
+
+// cacheEntry is cached response metadata.
+type cacheEntry struct {
+	size  int64
+	atime time.Time
+}
+
+// Transport is HTTP transport which caches GitHub API responses on disk.
+// Cached responses are revalidated with conditional requests;
+// GitHub API does not count 304 Not Modified responses against rate limit.
+// Responses are keyed by request URL and Accept header.
+// The least recently used responses are evicted when the cache exceeds its size.
+// Transport is used by creating GitHub API client with github.NewClient(t.Client()).
+type Transport struct {
+	dir     string
+	opts    CacheOptions
+	entries map[string]*cacheEntry
+	stats   CacheStats
+	mu      *sync.Mutex
+}
+
+// NewTransport creates a new caching HTTP transport which stores responses in dir and returns it.
+// Responses cached in dir by previous transports are reused.
+func NewTransport(dir string, opts ...CacheOption) (*Transport, error) {
+	copts := CacheOptions{}
+	for _, apply := range opts {
+		apply(&copts)
+	}
+
+	if copts.MaxSize <= 0 {
+		copts.MaxSize = DefaultCacheSize
+	}
+
+	if copts.Base == nil {
+		copts.Base = http.DefaultTransport
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Transport{
+		dir:     dir,
+		opts:    copts,
+		entries: make(map[string]*cacheEntry),
+		mu:      &sync.Mutex{},
+	}
+
+	for _, f := range files {
+		if f.IsDir() || filepath.Ext(f.Name()) != cacheExt {
+			continue
+		}
+
+		key := strings.TrimSuffix(f.Name(), cacheExt)
+		t.entries[key] = &cacheEntry{size: f.Size(), atime: f.ModTime()}
+		t.stats.Size += f.Size()
+	}
+
+	t.stats.Entries = len(t.entries)
+	t.evict()
+
+	return t, nil
+}
+
+// Client returns HTTP client which uses t.
+func (t *Transport) Client() *http.Client {
+	return &http.Client{Transport: t}
+}
+
+// Stats returns cache statistics.
+func (t *Transport) Stats() CacheStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.stats
+}
+
+// key returns cache key of req.
+func key(req *http.Request) string {
+	h := sha256.Sum256([]byte(req.URL.String() + "\n" + req.Header.Get("Accept")))
+	return hex.EncodeToString(h[:])
+}
+
+// path returns the path of the cache file with the given key.
+func (t *Transport) path(key string) string {
+	return filepath.Join(t.dir, key+cacheExt)
+}
+
+// RoundTrip implements http.RoundTripper.
+// Only GET requests are cached.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet || req.Header.Get("Range") != "" {
+		return t.opts.Base.RoundTrip(req)
+	}
+
+	k := key(req)
+
+	cached := t.load(k, req)
+
+	if cached != nil {
+		req = req.Clone(req.Context())
+		if etag := cached.Header.Get("ETag"); etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		if lastModified := cached.Header.Get("Last-Modified"); lastModified != "" {
+			req.Header.Set("If-Modified-Since", lastModified)
+		}
+	}
+
+	resp, err := t.opts.Base.RoundTrip(req)
+	if err != nil {
+		if cached != nil {
+			cached.Body.Close()
+		}
+		return nil, err
+	}
+
+	if cached != nil && resp.StatusCode == http.StatusNotModified {
+		// NOTE: rate limit headers of the fresh response must be preserved
+		for h, v := range resp.Header {
+			cached.Header[h] = v
+		}
+		resp.Body.Close()
+
+		t.mu.Lock()
+		t.stats.Hits++
+		t.mu.Unlock()
+
+		return cached, nil
+	}
+
+	if cached != nil {
+		cached.Body.Close()
+	}
+
+	t.mu.Lock()
+	t.stats.Misses++
+	t.mu.Unlock()
+
+	if resp.StatusCode == http.StatusOK && (resp.Header.Get("ETag") != "" || resp.Header.Get("Last-Modified") != "") {
+		if err := t.store(k, resp); err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+	}
+
+	return resp, nil
+}
+
+// load returns the cached response of req with the given key.
+// It returns nil if the response is not cached or fails to be read.
+func (t *Transport) load(key string, req *http.Request) *http.Response {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	entry, ok := t.entries[key]
+	if !ok {
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(t.path(key))
+	if err != nil {
+		t.remove(key)
+		return nil
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(data)), req)
+	if err != nil {
+		t.remove(key)
+		return nil
+	}
+
+	entry.atime = time.Now()
+
+	return resp
+}
+
+// store caches resp with the given key.
+// NOTE: resp body is read and replaced with an in-memory copy.
+func (t *Transport) store(key string, resp *http.Response) error {
+	data, err := httputil.DumpResponse(resp, true)
+	if err != nil {
+		return err
+	}
+
+	size := int64(len(data))
+	if size > t.opts.MaxSize {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err := ioutil.WriteFile(t.path(key), data, 0644); err != nil {
+		return err
+	}
+
+	if entry, ok := t.entries[key]; ok {
+		t.stats.Size -= entry.size
+	}
+
+	t.entries[key] = &cacheEntry{size: size, atime: time.Now()}
+	t.stats.Size += size
+	t.stats.Entries = len(t.entries)
+	t.evict()
+
+	return nil
+}
+
+// remove removes cached response with the given key.
+// NOTE: remove must be called with t.mu held.
+func (t *Transport) remove(key string) {
+	entry, ok := t.entries[key]
+	if !ok {
+		return
+	}
+
+	// nolint:errcheck
+	os.Remove(t.path(key))
+
+	delete(t.entries, key)
+	t.stats.Size -= entry.size
+	t.stats.Entries = len(t.entries)
+}
+
+// evict evicts the least recently used responses until the cache fits its size.
+// NOTE: evict must be called with t.mu held.
+func (t *Transport) evict() {
+	if t.stats.Size <= t.opts.MaxSize {
+		return
+	}
+
+	keys := make([]string, 0, len(t.entries))
+	for k := range t.entries {
+		keys = append(keys, k)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return t.entries[keys[i]].atime.Before(t.entries[keys[j]].atime)
+	})
+
+	for _, k := range keys {
+		if t.stats.Size <= t.opts.MaxSize {
+			break
+		}
+
+		t.remove(k)
+		t.stats.Evictions++
+	}
+}
@@ -0,0 +1,132 @@
+package star
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func newTestCacheServer() (*httptest.Server, *int) {
+	notModified := 0
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		etag := `"` + r.URL.Path + `"`
+		w.Header().Set("X-RateLimit-Remaining", "42")
+
+		if r.Header.Get("If-None-Match") == etag {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("ETag", etag)
+		// nolint:errcheck
+		w.Write([]byte("body of " + r.URL.Path))
+	}))
+
+	return ts, &notModified
+}
+
+func get(t *testing.T, c *http.Client, url string) *http.Response {
+	resp, err := c.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || string(body) != "body of /a" && string(body) != "body of /b" {
+		t.Fatalf("unexpected response: %d %s", resp.StatusCode, body)
+	}
+
+	return resp
+}
+
+func TestTransport(t *testing.T) {
+	ts, notModified := newTestCacheServer()
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tr, err := NewTransport(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get(t, tr.Client(), ts.URL+"/a")
+	resp := get(t, tr.Client(), ts.URL+"/a")
+
+	if *notModified != 1 {
+		t.Errorf("expected conditional requests: %d, got: %d", 1, *notModified)
+	}
+
+	if rem := resp.Header.Get("X-RateLimit-Remaining"); rem != "42" {
+		t.Errorf("expected rate limit header: %s, got: %s", "42", rem)
+	}
+
+	stats := tr.Stats()
+	if stats.Hits != 1 || stats.Misses != 1 || stats.Entries != 1 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+
+	// responses cached by previous transport are reused
+	tr, err = NewTransport(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get(t, tr.Client(), ts.URL+"/a")
+
+	if stats := tr.Stats(); stats.Hits != 1 || stats.Misses != 0 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestTransportEvict(t *testing.T) {
+	ts, _ := newTestCacheServer()
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tr, err := NewTransport(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get(t, tr.Client(), ts.URL+"/a")
+
+	size := tr.Stats().Size
+
+	tr, err = NewTransport(dir, MaxCacheSize(size+size/2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get(t, tr.Client(), ts.URL+"/b")
+
+	stats := tr.Stats()
+	if stats.Entries != 1 || stats.Evictions != 1 || stats.Size > size+size/2 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+
+	// the least recently used response has been evicted
+	get(t, tr.Client(), ts.URL+"/a")
+
+	if stats := tr.Stats(); stats.Misses != 2 {
+		t.Errorf("expected misses: %d, got: %d", 2, stats.Misses)
+	}
+}
@@ -0,0 +1,131 @@
+package star
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+// CheckpointStore stores incremental scraping checkpoints.
+// A checkpoint is the time of the most recent star seen by the scraper.
+type CheckpointStore interface {
+	// Get returns the checkpoint of the given user.
+	// It returns zero time if there is no checkpoint.
+	Get(ctx context.Context, user string) (time.Time, error)
+	// Put stores the checkpoint of the given user.
+	Put(ctx context.Context, user string, t time.Time) error
+}
+
+// MemCheckpoints is in-memory checkpoint store.
+type MemCheckpoints struct {
+	checkpoints map[string]time.Time
+	mu          *sync.Mutex
+}
+
+// NewMemCheckpoints creates a new in-memory checkpoint store and returns it.
+func NewMemCheckpoints() *MemCheckpoints {
+	return &MemCheckpoints{
+		checkpoints: make(map[string]time.Time),
+		mu:          &sync.Mutex{},
+	}
+}
+
+// Get returns the checkpoint of the given user.
+func (m *MemCheckpoints) Get(ctx context.Context, user string) (time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.checkpoints[user], nil
+}
+
+// Put stores the checkpoint of the given user.
+func (m *MemCheckpoints) Put(ctx context.Context, user string, t time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.checkpoints[user] = t
+
+	return nil
+}
+
+// FileCheckpoints is checkpoint store persisted in a local JSON file.
+type FileCheckpoints struct {
+	path string
+	mu   *sync.Mutex
+}
+
+// NewFileCheckpoints creates a new checkpoint store persisted in the file with the given path.
+// The file is created when the first checkpoint is stored.
+func NewFileCheckpoints(path string) *FileCheckpoints {
+	return &FileCheckpoints{
+		path: path,
+		mu:   &sync.Mutex{},
+	}
+}
+
+// read reads all the checkpoints from the file.
+func (f *FileCheckpoints) read() (map[string]time.Time, error) {
+	checkpoints := make(map[string]time.Time)
+
+	data, err := ioutil.ReadFile(f.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return checkpoints, nil
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &checkpoints); err != nil {
+		return nil, err
+	}
+
+	return checkpoints, nil
+}
+
+// Get returns the checkpoint of the given user.
+func (f *FileCheckpoints) Get(ctx context.Context, user string) (time.Time, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	checkpoints, err := f.read()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return checkpoints[user], nil
+}
+
+// Put stores the checkpoint of the given user.
+// NOTE: the file is replaced atomically so a failed write does not lose the checkpoints.
+func (f *FileCheckpoints) Put(ctx context.Context, user string, t time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	checkpoints, err := f.read()
+	if err != nil {
+		return err
+	}
+
+	checkpoints[user] = t
+
+	return writeJSON(f.path, checkpoints)
+}
+
+// writeJSON atomically replaces the file with the given path with v encoded as JSON.
+func writeJSON(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
@@ -4,17 +4,39 @@ const (
 	// version is GitHub API version
 	version = "v3"
 
+	// stargazerRes is stargazer resource name
+	stargazerRes = "stargazer"
+	// stargazerGroup is stargazer resource group
+	stargazerGroup = "stargazers"
+	// stargazerRel is the stargazer-repo relation
+	stargazerRel = "starred"
+
 	// ownerRes is owner resource name
 	ownerRes = "owner"
 	// ownerGroup is owner resource group
 	ownerGroup = "owners"
 	// ownerRol is the owner-repo relation
 	ownerRel = "owns"
+	// ownerTypeAttr is the GitHub account type of the owner
+	ownerTypeAttr = "owner_type"
+
+	// contributorRes is contributor resource name
+	contributorRes = "contributor"
+	// contributorGroup is contributor resource group
+	contributorGroup = "contributors"
+	// contributorRel is the contributor-repo relation
+	contributorRel = "contributes"
 
 	// repoRes is repo resource name
 	repoRes = "repo"
 	// repoGroup is repo resource group
 	repoGroup = "repos"
+	// forkRel is the fork-upstream repo relation
+	forkRel = "forkOf"
+	// upstreamParent marks the link to the repo the fork was created from
+	upstreamParent = "parent"
+	// upstreamSource marks the link to the root repo of the fork network
+	upstreamSource = "source"
 
 	// topicRes is topic resource name
 	topicRes = "topic"
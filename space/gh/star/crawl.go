@@ -0,0 +1,247 @@
+package star
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	"github.com/google/go-github/v32/github"
+	"github.com/milosgajdos/netscrape/pkg/attrs"
+	"github.com/milosgajdos/netscrape/pkg/space"
+	"github.com/milosgajdos/netscrape/pkg/space/entity"
+)
+
+// frontier records the users and repos discovered by crawling.
+// NOTE: its size is proportional to the number of crawled users and repos,
+// which is bounded by the configured crawling Depth and FanOut.
+// It is safe to call frontier methods on nil frontier.
+type frontier struct {
+	// users are the visited users
+	users map[string]bool
+	// repos are the discovered repos
+	repos map[string]bool
+	// pending are the repos discovered at the current level
+	// NOTE: only the fields needed for crawling are kept
+	pending []*github.Repository
+	// mu synchronizes access to frontier
+	mu *sync.Mutex
+}
+
+// newFrontier creates a new empty frontier and returns it.
+func newFrontier() *frontier {
+	return &frontier{
+		users: make(map[string]bool),
+		repos: make(map[string]bool),
+		mu:    &sync.Mutex{},
+	}
+}
+
+// visit records user with the given login as visited.
+// It returns true if the user has not been visited before.
+func (f *frontier) visit(login string) bool {
+	if f == nil {
+		return false
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.users[login] {
+		return false
+	}
+	f.users[login] = true
+
+	return true
+}
+
+// add records repo as discovered at the current level
+// unless it has been discovered before.
+func (f *frontier) add(repo *github.Repository) {
+	if f == nil {
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.repos[repo.GetNodeID()] {
+		return
+	}
+	f.repos[repo.GetNodeID()] = true
+
+	f.pending = append(f.pending, &github.Repository{
+		NodeID: repo.NodeID,
+		Name:   repo.Name,
+		Owner:  &github.User{Login: repo.GetOwner().Login},
+	})
+}
+
+// next returns the repos discovered at the current level and starts a new level.
+func (f *frontier) next() []*github.Repository {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	repos := f.pending
+	f.pending = nil
+
+	return repos
+}
+
+// fanOut returns the crawling fan-out limit at the given level.
+// Paging is returned if no limit is configured.
+func (s *scraper) fanOut(level int) int {
+	if len(s.opts.FanOut) == 0 {
+		return s.opts.Paging
+	}
+
+	if level > len(s.opts.FanOut) {
+		return s.opts.FanOut[len(s.opts.FanOut)-1]
+	}
+
+	return s.opts.FanOut[level-1]
+}
+
+// crawl crawls the stargazers of the repos in frontier f up to the configured depth.
+// At every level the stargazers of the repos discovered at the previous level
+// are sampled and the repos they starred are mapped into top.
+// If scraping is partial, failed repos are collected in fails and the remaining repos are still crawled.
+func (s *scraper) crawl(ctx context.Context, f *frontier, top space.Top, resMap map[string]space.Resource, fails *failures) error {
+	for level := 1; level <= s.opts.Depth; level++ {
+		repos := f.next()
+		if len(repos) == 0 {
+			return nil
+		}
+
+		reposChan := make(chan *github.Repository, s.opts.Workers)
+		// NOTE: errChan is buffered so every worker can report its result
+		errChan := make(chan error, s.opts.Workers)
+		done := make(chan struct{})
+
+		for i := 0; i < s.opts.Workers; i++ {
+			go func(level int) {
+				var err error
+				// NOTE: the remaining repos are drained on error
+				for repo := range reposChan {
+					if err == nil {
+						err = s.crawlRepo(ctx, level, repo, f, top, resMap)
+						if err != nil && fails.add(ctx, &Failure{Repo: repoName(repo), Err: err}) {
+							err = nil
+						}
+					}
+				}
+				errChan <- err
+			}(level)
+		}
+
+		go func() {
+			defer close(reposChan)
+
+			for _, repo := range repos {
+				select {
+				case reposChan <- repo:
+				case <-ctx.Done():
+					return
+				case <-done:
+					return
+				}
+			}
+		}()
+
+		var err error
+
+		for i := 0; i < s.opts.Workers; i++ {
+			if e := <-errChan; e != nil && err == nil {
+				err = e
+				close(done)
+			}
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if ctx.Err() != nil {
+			return fails.canceled(ctx)
+		}
+	}
+
+	return nil
+}
+
+// crawlRepo samples the stargazers of GH repo at the given level and maps
+// the repos starred by the stargazers which have not been visited.
+// Only the repos which match the configured filter are mapped and crawled further.
+func (s *scraper) crawlRepo(ctx context.Context, level int, repo *github.Repository, f *frontier, top space.Top, resMap map[string]space.Resource) error {
+	n := s.fanOut(level)
+
+	var stargazers []*github.Stargazer
+
+	err := s.rate.do(ctx, func() (*github.Response, error) {
+		var (
+			resp *github.Response
+			err  error
+		)
+		stargazers, resp, err = s.gh.Activity.ListStargazers(ctx, repo.GetOwner().GetLogin(), repo.GetName(), &github.ListOptions{PerPage: n})
+		return resp, err
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(stargazers) > n {
+		stargazers = stargazers[:n]
+	}
+
+	for _, sg := range stargazers {
+		login := sg.GetUser().GetLogin()
+		if login == "" || !f.visit(login) {
+			continue
+		}
+
+		a, err := attrs.New()
+		if err != nil {
+			return err
+		}
+		a.Set("depth", strconv.Itoa(level))
+
+		stargazer, err := s.mapStargazer(ctx, login, top, resMap, entity.WithAttrs(a))
+		if err != nil {
+			return err
+		}
+
+		var stars []*github.StarredRepository
+
+		opts := &github.ActivityListStarredOptions{
+			ListOptions: github.ListOptions{PerPage: n},
+		}
+
+		err = s.rate.do(ctx, func() (*github.Response, error) {
+			var (
+				resp *github.Response
+				err  error
+			)
+			stars, resp, err = s.gh.Activity.ListStarred(ctx, login, opts)
+			return resp, err
+		})
+		if err != nil {
+			return err
+		}
+
+		if len(stars) > n {
+			stars = stars[:n]
+		}
+
+		for _, star := range stars {
+			if !s.opts.Filter.match(star) {
+				continue
+			}
+
+			if err := s.mapRepo(ctx, stargazer, star, nil, top, resMap); err != nil {
+				return err
+			}
+			f.add(star.Repository)
+		}
+	}
+
+	return nil
+}
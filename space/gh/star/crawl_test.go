@@ -0,0 +1,99 @@
+package star
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestMapCrawl(t *testing.T) {
+	now := time.Now()
+
+	ts := newTestServer([]testStar{newTestStar(0, "owner", now)})
+	defer ts.Close()
+
+	ts.users["alice"] = []testStar{newTestStar(1, "owner", now)}
+	ts.users["bob"] = []testStar{newTestStar(0, "owner", now), newTestStar(2, "owner", now)}
+	ts.users["carol"] = []testStar{newTestStar(3, "owner", now)}
+	ts.users["dave"] = []testStar{newTestStar(4, "owner", now), newTestStar(5, "owner", now)}
+	ts.users["erin"] = []testStar{newTestStar(6, "owner", now)}
+
+	stargazers := func(logins ...string) []map[string]interface{} {
+		var sx []map[string]interface{}
+		for _, l := range logins {
+			sx = append(sx, map[string]interface{}{"user": map[string]string{"login": l}})
+		}
+		return sx
+	}
+
+	// NOTE: carol is not sampled at level 1 and erin at level 2
+	ts.handle("/repos/owner/repo0/stargazers", stargazers("alice", "bob", "carol"))
+	ts.handle("/repos/owner/repo1/stargazers", stargazers("dave"))
+	ts.handle("/repos/owner/repo2/stargazers", stargazers("alice", "erin"))
+
+	s, err := NewScraper(ts.client(), Crawl(2, 2, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	top, err := testMap(context.Background(), s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := repoNames(context.Background(), top)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fmt.Sprint(names) != "[repo0 repo1 repo2 repo4]" {
+		t.Errorf("expected repos: [repo0 repo1 repo2 repo4], got: %v", names)
+	}
+
+	ents, err := top.Entities(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var users []string
+	for _, e := range ents {
+		if e.Resource().Name() == stargazerRes {
+			users = append(users, e.Name()+":"+e.Attrs().Get("depth"))
+		}
+	}
+
+	sort.Strings(users)
+
+	if fmt.Sprint(users) != "[alice:1 bob:1 dave:2 me:]" {
+		t.Errorf("expected stargazers: [alice:1 bob:1 dave:2 me:], got: %v", users)
+	}
+}
+
+func TestFanOut(t *testing.T) {
+	testCases := []struct {
+		name   string
+		fanout []int
+		want   []int
+	}{
+		{name: "Default", fanout: nil, want: []int{paging, paging, paging}},
+		{name: "Single", fanout: []int{3}, want: []int{3, 3, 3}},
+		{name: "PerLevel", fanout: []int{5, 2}, want: []int{5, 2, 2}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := NewScraper(nil, Crawl(3, tc.fanout...))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for level, want := range tc.want {
+				if n := s.fanOut(level + 1); n != want {
+					t.Errorf("expected level %d fan-out: %d, got: %d", level+1, want, n)
+				}
+			}
+		})
+	}
+}
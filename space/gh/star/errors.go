@@ -0,0 +1,89 @@
+package star
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrInvalidState is returned when resuming scrape from invalid state
+	ErrInvalidState = errors.New("ErrInvalidState")
+	// ErrGraphQL is returned when GitHub GraphQL API query fails
+	ErrGraphQL = errors.New("ErrGraphQL")
+)
+
+// Failure is a failure to scrape a user, a page of starred repos or a repo.
+type Failure struct {
+	// User is the scraped user; empty for the authenticated user
+	User string
+	// Page is the number of the failed page; zero if not known
+	Page int
+	// Repo is the full name of the failed repo; empty if not known
+	Repo string
+	// Err is the failure error
+	Err error
+}
+
+// Error implements error interface.
+func (f *Failure) Error() string {
+	var scope []string
+
+	if f.User != "" {
+		scope = append(scope, fmt.Sprintf("user %s", f.User))
+	}
+
+	if f.Page > 0 {
+		scope = append(scope, fmt.Sprintf("page %d", f.Page))
+	}
+
+	if f.Repo != "" {
+		scope = append(scope, fmt.Sprintf("repo %s", f.Repo))
+	}
+
+	if len(scope) == 0 {
+		return f.Err.Error()
+	}
+
+	return strings.Join(scope, " ") + ": " + f.Err.Error()
+}
+
+// Unwrap returns the failure error.
+func (f *Failure) Unwrap() error {
+	return f.Err
+}
+
+// MapError is returned with partial topology when partial scraping fails or is canceled.
+type MapError struct {
+	// Failures are the failed users, pages and repos.
+	// At most MaxFailures failures are collected.
+	Failures []*Failure
+	// Omitted is the number of failures which have not been collected
+	Omitted int
+	// Err is context error if scraping has been canceled
+	Err error
+}
+
+// Error implements error interface.
+func (e *MapError) Error() string {
+	msgs := make([]string, 0, len(e.Failures)+1)
+
+	if e.Err != nil {
+		msgs = append(msgs, e.Err.Error())
+	}
+
+	for _, f := range e.Failures {
+		msgs = append(msgs, f.Error())
+	}
+
+	if e.Omitted > 0 {
+		msgs = append(msgs, fmt.Sprintf("%d more failures omitted", e.Omitted))
+	}
+
+	return fmt.Sprintf("%d failures: %s", len(e.Failures)+e.Omitted, strings.Join(msgs, "; "))
+}
+
+// Unwrap returns context error if scraping has been canceled.
+func (e *MapError) Unwrap() error {
+	return e.Err
+}
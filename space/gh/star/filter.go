@@ -0,0 +1,94 @@
+package star
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v32/github"
+)
+
+// Filter selects the starred repos which are mapped.
+// Zero Filter selects all repos.
+type Filter struct {
+	// Languages are included primary repo languages
+	Languages []string
+	// ExcludeLanguages are excluded primary repo languages
+	ExcludeLanguages []string
+	// Topics are included repo topics
+	Topics []string
+	// ExcludeTopics are excluded repo topics
+	ExcludeTopics []string
+	// Owners are included repo owners
+	Owners []string
+	// ExcludeOwners are excluded repo owners
+	ExcludeOwners []string
+	// Since is the earliest included star time
+	Since time.Time
+	// Until is the time before which repos must be starred
+	Until time.Time
+	// MinStars is the minimum number of repo stargazers
+	MinStars int
+	// Archived is the archived status of included repos
+	Archived *bool
+}
+
+// match returns true if starred repo matches the filter.
+// Languages, topics and owners are matched case-insensitively.
+func (f Filter) match(star *github.StarredRepository) bool {
+	repo := star.GetRepository()
+
+	if !included(f.Languages, f.ExcludeLanguages, repo.GetLanguage()) {
+		return false
+	}
+
+	if !included(f.Owners, f.ExcludeOwners, owner(repo)) {
+		return false
+	}
+
+	if !included(f.Topics, f.ExcludeTopics, repo.Topics...) {
+		return false
+	}
+
+	starredAt := star.GetStarredAt().Time
+
+	if !f.Since.IsZero() && starredAt.Before(f.Since) {
+		return false
+	}
+
+	if !f.Until.IsZero() && !starredAt.Before(f.Until) {
+		return false
+	}
+
+	if repo.GetStargazersCount() < f.MinStars {
+		return false
+	}
+
+	if f.Archived != nil && repo.GetArchived() != *f.Archived {
+		return false
+	}
+
+	return true
+}
+
+// included returns true if any of vals is in include, if include is not empty,
+// and none of vals is in exclude.
+func included(include, exclude []string, vals ...string) bool {
+	if len(include) > 0 && !containsAny(include, vals) {
+		return false
+	}
+
+	return !containsAny(exclude, vals)
+}
+
+// containsAny returns true if any of vals is in list.
+func containsAny(list, vals []string) bool {
+	for _, v := range vals {
+		for _, l := range list {
+			if strings.EqualFold(l, v) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
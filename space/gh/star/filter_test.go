@@ -0,0 +1,104 @@
+package star
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v32/github"
+)
+
+func TestFilterMatch(t *testing.T) {
+	now := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	star := &github.StarredRepository{
+		StarredAt: &github.Timestamp{Time: now},
+		Repository: &github.Repository{
+			Name:            github.String("repo"),
+			Language:        github.String("Go"),
+			Topics:          []string{"graph", "cli"},
+			StargazersCount: github.Int(10),
+			Archived:        github.Bool(false),
+			Owner:           &github.User{Login: github.String("Owner")},
+		},
+	}
+
+	testCases := []struct {
+		name   string
+		filter Filter
+		want   bool
+	}{
+		{"Zero", Filter{}, true},
+		{"Language", Filter{Languages: []string{"go"}}, true},
+		{"OtherLanguage", Filter{Languages: []string{"Rust"}}, false},
+		{"ExcludeLanguage", Filter{ExcludeLanguages: []string{"GO"}}, false},
+		{"Topic", Filter{Topics: []string{"cli", "web"}}, true},
+		{"OtherTopic", Filter{Topics: []string{"web"}}, false},
+		{"ExcludeTopic", Filter{ExcludeTopics: []string{"graph"}}, false},
+		{"Owner", Filter{Owners: []string{"owner"}}, true},
+		{"ExcludeOwner", Filter{ExcludeOwners: []string{"owner"}}, false},
+		{"Since", Filter{Since: now}, true},
+		{"SinceAfter", Filter{Since: now.Add(time.Second)}, false},
+		{"Until", Filter{Until: now.Add(time.Second)}, true},
+		{"UntilBefore", Filter{Until: now}, false},
+		{"MinStars", Filter{MinStars: 10}, true},
+		{"MoreStars", Filter{MinStars: 11}, false},
+		{"NotArchived", Filter{Archived: github.Bool(false)}, true},
+		{"Archived", Filter{Archived: github.Bool(true)}, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.filter.match(star); got != tc.want {
+				t.Errorf("expected match: %v, got: %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestMapFilter(t *testing.T) {
+	now := time.Now()
+
+	var stars []testStar
+	for i := 0; i < 4; i++ {
+		s := newTestStar(i, "owner", now)
+		s.Repo.Stars = i
+		stars = append(stars, s)
+	}
+	stars[2].Repo.Language = "Rust"
+	stars[3].Repo.Topics = []string{"web"}
+
+	ts := newTestServer(stars)
+	defer ts.Close()
+
+	s, err := NewScraper(ts.client(), MinStars(1), ExcludeLanguages("rust"), IncludeTopics("graph"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	top, err := testMap(context.Background(), s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := repoNames(context.Background(), top)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fmt.Sprint(names) != "[repo1]" {
+		t.Errorf("expected repos: [repo1], got: %v", names)
+	}
+
+	ents, err := top.Entities(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, e := range ents {
+		if e.Resource().Name() == langRes && e.Name() != "go" {
+			t.Errorf("unexpected lang: %s", e.Name())
+		}
+	}
+}
@@ -0,0 +1,316 @@
+package star
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v32/github"
+)
+
+const (
+	// graphqlQuery queries a page of starred repos of the user selected by %s
+	graphqlQuery = `query(%s$first: Int!, $after: String) {
+  %s {
+    starredRepositories(first: $first, after: $after, orderBy: {field: STARRED_AT, direction: DESC}) {
+      pageInfo { hasNextPage endCursor }
+      edges {
+        starredAt
+        node {
+          id
+          name
+          resourcePath
+          description
+          homepageUrl
+          stargazers { totalCount }
+          forkCount
+          issues(states: OPEN) { totalCount }
+          pullRequests(states: OPEN) { totalCount }
+          licenseInfo { spdxId key }
+          defaultBranchRef { name }
+          createdAt
+          updatedAt
+          pushedAt
+          isArchived
+          isFork
+          isTemplate
+          owner { __typename login }
+          primaryLanguage { name }
+          repositoryTopics(first: 100) { nodes { topic { name } } }
+          languages(first: 100) { edges { size node { name } } }
+        }
+      }
+    }
+  }
+}`
+
+	// graphqlRateLimited is the type of GraphQL API rate limit error
+	graphqlRateLimited = "RATE_LIMITED"
+	// graphqlMaxPaging is the maximum GraphQL API page size
+	graphqlMaxPaging = 100
+)
+
+// graphqlRequest is GitHub GraphQL API request.
+type graphqlRequest struct {
+	Query     string                 `json:"query"`
+	Variables map[string]interface{} `json:"variables"`
+}
+
+// graphqlCount is GitHub GraphQL API total count.
+type graphqlCount struct {
+	TotalCount int `json:"totalCount"`
+}
+
+// graphqlRepo is GitHub GraphQL API repository.
+type graphqlRepo struct {
+	ID           string       `json:"id"`
+	Name         string       `json:"name"`
+	ResourcePath string       `json:"resourcePath"`
+	Description  string       `json:"description"`
+	HomepageURL  string       `json:"homepageUrl"`
+	Stargazers   graphqlCount `json:"stargazers"`
+	ForkCount    int          `json:"forkCount"`
+	Issues       graphqlCount `json:"issues"`
+	PullRequests graphqlCount `json:"pullRequests"`
+	LicenseInfo  *struct {
+		SPDXID string `json:"spdxId"`
+		Key    string `json:"key"`
+	} `json:"licenseInfo"`
+	DefaultBranchRef *struct {
+		Name string `json:"name"`
+	} `json:"defaultBranchRef"`
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+	PushedAt   time.Time `json:"pushedAt"`
+	IsArchived bool      `json:"isArchived"`
+	IsFork     bool      `json:"isFork"`
+	IsTemplate bool      `json:"isTemplate"`
+	Owner      struct {
+		Type  string `json:"__typename"`
+		Login string `json:"login"`
+	} `json:"owner"`
+	PrimaryLanguage *struct {
+		Name string `json:"name"`
+	} `json:"primaryLanguage"`
+	RepositoryTopics struct {
+		Nodes []struct {
+			Topic struct {
+				Name string `json:"name"`
+			} `json:"topic"`
+		} `json:"nodes"`
+	} `json:"repositoryTopics"`
+	Languages struct {
+		Edges []struct {
+			Size int `json:"size"`
+			Node struct {
+				Name string `json:"name"`
+			} `json:"node"`
+		} `json:"edges"`
+	} `json:"languages"`
+}
+
+// graphqlStars is GitHub GraphQL API starred repositories connection.
+type graphqlStars struct {
+	PageInfo struct {
+		HasNextPage bool   `json:"hasNextPage"`
+		EndCursor   string `json:"endCursor"`
+	} `json:"pageInfo"`
+	Edges []struct {
+		StarredAt time.Time   `json:"starredAt"`
+		Node      graphqlRepo `json:"node"`
+	} `json:"edges"`
+}
+
+// graphqlUser is GitHub GraphQL API user.
+type graphqlUser struct {
+	StarredRepositories graphqlStars `json:"starredRepositories"`
+}
+
+// graphqlResponse is GitHub GraphQL API response.
+type graphqlResponse struct {
+	Data struct {
+		Viewer *graphqlUser `json:"viewer"`
+		User   *graphqlUser `json:"user"`
+	} `json:"data"`
+	Errors []struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// rateLimitError returns *github.RateLimitError if GraphQL API query has been rate limited.
+// NOTE: GitHub GraphQL API reports rate limiting as a query error with HTTP 200 status.
+// If resp carries no rate limit reset time, the query is retried after abuseWait.
+func (r *graphqlResponse) rateLimitError(resp *github.Response) error {
+	for _, e := range r.Errors {
+		if e.Type != graphqlRateLimited {
+			continue
+		}
+
+		rateErr := &github.RateLimitError{Message: e.Message}
+		if resp != nil {
+			rateErr.Rate = resp.Rate
+			rateErr.Response = resp.Response
+		}
+
+		if rateErr.Rate.Reset.IsZero() {
+			rateErr.Rate.Reset = github.Timestamp{Time: time.Now().Add(abuseWait)}
+		}
+
+		return rateErr
+	}
+
+	return nil
+}
+
+// graphqlURL returns GitHub GraphQL API endpoint relative to GitHub REST API base URL.
+// NOTE: GitHub Enterprise serves REST API on /api/v3/ and GraphQL API on /api/graphql.
+func (s *scraper) graphqlURL() string {
+	if strings.HasSuffix(s.gh.BaseURL.Path, "/api/v3/") {
+		return "../graphql"
+	}
+
+	return "graphql"
+}
+
+// fetchGraphQLPage fetches the page of starred repos at cursor c from GitHub GraphQL API
+// and advances the cursor to the next page. It returns false if there is no next page.
+// NOTE: GraphQL API always returns the most recently starred repos first.
+func (s *scraper) fetchGraphQLPage(ctx context.Context, c *cursor) (*Page, bool, error) {
+	vars := map[string]interface{}{
+		"first": s.opts.Paging,
+	}
+
+	if c.after != "" {
+		vars["after"] = c.after
+	}
+
+	decl, root := "", "viewer"
+	if c.user != "" {
+		decl, root = "$login: String!, ", "user(login: $login)"
+		vars["login"] = c.user
+	}
+
+	body := &graphqlRequest{
+		Query:     fmt.Sprintf(graphqlQuery, decl, root),
+		Variables: vars,
+	}
+
+	var out graphqlResponse
+
+	err := s.rate.do(ctx, func() (*github.Response, error) {
+		req, err := s.gh.NewRequest("POST", s.graphqlURL(), body)
+		if err != nil {
+			return nil, err
+		}
+
+		out = graphqlResponse{}
+
+		resp, err := s.gh.Do(ctx, req, &out)
+		if err != nil {
+			return resp, err
+		}
+
+		return resp, out.rateLimitError(resp)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	if len(out.Errors) > 0 {
+		msgs := make([]string, len(out.Errors))
+		for i, e := range out.Errors {
+			msgs[i] = e.Message
+		}
+		return nil, false, fmt.Errorf("%s: %w", strings.Join(msgs, "; "), ErrGraphQL)
+	}
+
+	user := out.Data.Viewer
+	if c.user != "" {
+		user = out.Data.User
+	}
+
+	if user == nil {
+		return nil, false, fmt.Errorf("user %q not found: %w", c.user, ErrGraphQL)
+	}
+
+	stars := user.StarredRepositories
+
+	page := &Page{
+		Number:    c.page,
+		Size:      s.opts.Paging,
+		Stars:     make([]*github.StarredRepository, len(stars.Edges)),
+		Cursor:    stars.PageInfo.EndCursor,
+		Languages: make(map[string]map[string]int),
+	}
+
+	for i, edge := range stars.Edges {
+		page.Stars[i] = &github.StarredRepository{
+			StarredAt:  &github.Timestamp{Time: edge.StarredAt},
+			Repository: s.graphqlRepository(&edge.Node),
+		}
+
+		langs := make(map[string]int)
+		for _, l := range edge.Node.Languages.Edges {
+			langs[l.Node.Name] = l.Size
+		}
+		page.Languages[edge.Node.ID] = langs
+	}
+
+	c.page++
+	c.after = stars.PageInfo.EndCursor
+
+	return page, stars.PageInfo.HasNextPage, nil
+}
+
+// graphqlRepository converts GraphQL API repository to REST API repository.
+func (s *scraper) graphqlRepository(r *graphqlRepo) *github.Repository {
+	// NOTE: REST API repository URL is its API URL
+	url := strings.TrimSuffix(s.gh.BaseURL.String(), "/") + "/repos" + r.ResourcePath
+
+	repo := &github.Repository{
+		NodeID:      github.String(r.ID),
+		Name:        github.String(r.Name),
+		URL:         github.String(url),
+		Description: github.String(r.Description),
+		Homepage:    github.String(r.HomepageURL),
+		// NOTE: REST API watchers are stargazers
+		StargazersCount: github.Int(r.Stargazers.TotalCount),
+		WatchersCount:   github.Int(r.Stargazers.TotalCount),
+		ForksCount:      github.Int(r.ForkCount),
+		// NOTE: REST API open issues include open pull requests
+		OpenIssuesCount: github.Int(r.Issues.TotalCount + r.PullRequests.TotalCount),
+		CreatedAt:       &github.Timestamp{Time: r.CreatedAt},
+		UpdatedAt:       &github.Timestamp{Time: r.UpdatedAt},
+		PushedAt:        &github.Timestamp{Time: r.PushedAt},
+		Archived:        github.Bool(r.IsArchived),
+		Fork:            github.Bool(r.IsFork),
+		IsTemplate:      github.Bool(r.IsTemplate),
+		Owner: &github.User{
+			Login: github.String(r.Owner.Login),
+			Type:  github.String(r.Owner.Type),
+		},
+	}
+
+	if r.LicenseInfo != nil {
+		repo.License = &github.License{
+			SPDXID: github.String(r.LicenseInfo.SPDXID),
+			Key:    github.String(r.LicenseInfo.Key),
+		}
+	}
+
+	if r.DefaultBranchRef != nil {
+		repo.DefaultBranch = github.String(r.DefaultBranchRef.Name)
+	}
+
+	if r.PrimaryLanguage != nil {
+		repo.Language = github.String(r.PrimaryLanguage.Name)
+	}
+
+	for _, t := range r.RepositoryTopics.Nodes {
+		repo.Topics = append(repo.Topics, t.Topic.Name)
+	}
+
+	return repo
+}
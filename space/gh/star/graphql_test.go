@@ -0,0 +1,354 @@
+package star
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/milosgajdos/netscrape/pkg/space"
+)
+
+// graphql serves paginated starred repos from fake GitHub GraphQL API.
+// Query variables are recorded in ts queries.
+// Repo language sizes are served from langs keyed by repo name.
+func (ts *testServer) graphql(langs map[string]map[string]int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req graphqlRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		q := make(url.Values)
+		for k, v := range req.Variables {
+			q.Set(k, fmt.Sprint(v))
+		}
+
+		ts.mu.Lock()
+		ts.queries = append(ts.queries, q)
+		ts.mu.Unlock()
+
+		served, root := ts.stars, "viewer"
+		if login, ok := req.Variables["login"].(string); ok {
+			served, root = ts.users[login], "user"
+		}
+
+		stars := make([]testStar, len(served))
+		copy(stars, served)
+
+		sort.SliceStable(stars, func(i, j int) bool {
+			return stars[i].StarredAt.After(stars[j].StarredAt)
+		})
+
+		start := 0
+		if after, ok := req.Variables["after"].(string); ok {
+			start, _ = strconv.Atoi(after)
+		}
+
+		end := start + int(req.Variables["first"].(float64))
+		if end > len(stars) {
+			end = len(stars)
+		}
+
+		var edges []interface{}
+
+		for _, s := range stars[start:end] {
+			var topics []interface{}
+			for _, t := range s.Repo.Topics {
+				topics = append(topics, map[string]interface{}{"topic": map[string]string{"name": t}})
+			}
+
+			var languages []interface{}
+			for name, size := range langs[s.Repo.Name] {
+				languages = append(languages, map[string]interface{}{"size": size, "node": map[string]string{"name": name}})
+			}
+
+			edges = append(edges, map[string]interface{}{
+				"starredAt": s.StarredAt,
+				"node": map[string]interface{}{
+					"id":               s.Repo.NodeID,
+					"name":             s.Repo.Name,
+					"resourcePath":     "/" + s.Repo.Owner.Login + "/" + s.Repo.Name,
+					"stargazers":       map[string]int{"totalCount": s.Repo.Stars},
+					"isFork":           s.Repo.Fork,
+					"owner":            map[string]string{"__typename": s.Repo.Owner.Type, "login": s.Repo.Owner.Login},
+					"primaryLanguage":  map[string]string{"name": s.Repo.Language},
+					"repositoryTopics": map[string]interface{}{"nodes": topics},
+					"languages":        map[string]interface{}{"edges": languages},
+				},
+			})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		// nolint:errcheck
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{
+				root: map[string]interface{}{
+					"starredRepositories": map[string]interface{}{
+						"pageInfo": map[string]interface{}{"hasNextPage": end < len(stars), "endCursor": strconv.Itoa(end)},
+						"edges":    edges,
+					},
+				},
+			},
+		})
+	}
+}
+
+// dump returns sorted entities and links of top.
+// git_url attributes are omitted as they depend on the API endpoint.
+func dump(ctx context.Context, top space.Top) ([]string, error) {
+	ents, err := top.Entities(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []string
+
+	for _, e := range ents {
+		var kv []string
+		for _, k := range e.Attrs().Keys() {
+			if k != "git_url" {
+				kv = append(kv, k+"="+e.Attrs().Get(k))
+			}
+		}
+		sort.Strings(kv)
+		out = append(out, fmt.Sprintf("%s %s/%s %v", e.UID().Value(), e.Resource().Name(), e.Name(), kv))
+
+		links, err := top.Links(ctx, e.UID())
+		if err != nil {
+			if errors.Is(err, space.ErrEntityNotFound) {
+				continue
+			}
+			return nil, err
+		}
+
+		for _, l := range links {
+			var kv []string
+			for _, k := range l.Attrs().Keys() {
+				kv = append(kv, k+"="+l.Attrs().Get(k))
+			}
+			sort.Strings(kv)
+			out = append(out, fmt.Sprintf("%s->%s %v", l.From().Value(), l.To().Value(), kv))
+		}
+	}
+
+	sort.Strings(out)
+
+	return out, nil
+}
+
+func TestMapGraphQL(t *testing.T) {
+	now := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	var stars []testStar
+	for i := 0; i < 3; i++ {
+		s := newTestStar(i, "owner", now.Add(time.Duration(i)*time.Hour))
+		s.Repo.Stars = 10 * i
+		stars = append(stars, s)
+	}
+
+	ts := newTestServer(stars)
+	defer ts.Close()
+
+	ts.users["alice"] = []testStar{newTestStar(3, "org", now)}
+	ts.users["alice"][0].Repo.Owner.Type = "Organization"
+
+	langs := map[string]map[string]int{
+		"repo0": {"Go": 300, "Shell": 100},
+		"repo1": {"Go": 100},
+		"repo2": {},
+		"repo3": {"C": 1},
+	}
+
+	var restLangs int32
+
+	for _, s := range append(stars, ts.users["alice"]...) {
+		sizes := langs[s.Repo.Name]
+		ts.mux.HandleFunc("/repos/"+s.Repo.Owner.Login+"/"+s.Repo.Name+"/languages", func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&restLangs, 1)
+			w.Header().Set("Content-Type", "application/json")
+			// nolint:errcheck
+			json.NewEncoder(w).Encode(sizes)
+		})
+	}
+
+	ts.mux.HandleFunc("/graphql", ts.graphql(langs))
+
+	opts := []Option{User(""), User("alice"), Paging(2), Languages(true), RepoAttrs(StarsAttr, ForkAttr)}
+
+	s, err := NewScraper(ts.client(), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restTop, err := testMap(context.Background(), s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if restLangs != int32(len(langs)) {
+		t.Fatalf("expected REST languages requests: %d, got: %d", len(langs), restLangs)
+	}
+
+	s, err = NewScraper(ts.client(), append(opts, GraphQL(true))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	graphqlTop, err := testMap(context.Background(), s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if restLangs != int32(len(langs)) {
+		t.Errorf("expected no GraphQL languages requests, got: %d", restLangs-int32(len(langs)))
+	}
+
+	want, err := dump(context.Background(), restTop)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := dump(context.Background(), graphqlTop)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("expected topology:\n%s\ngot:\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestMapGraphQLResume(t *testing.T) {
+	now := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	var stars []testStar
+	for i := 0; i < 3; i++ {
+		stars = append(stars, newTestStar(i, "owner", now.Add(time.Duration(i)*time.Hour)))
+	}
+
+	ts := newTestServer(stars)
+	defer ts.Close()
+
+	ts.mux.HandleFunc("/graphql", ts.graphql(nil))
+
+	state := NewMemState()
+
+	// NOTE: the first page has been mapped by the interrupted scrape
+	if err := state.Append(context.Background(), "", &Page{Number: 1, Size: 1, Cursor: "1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewScraper(ts.client(), Paging(1), GraphQL(true), State(state), Resume(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	top, err := testMap(context.Background(), s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := repoNames(context.Background(), top)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fmt.Sprint(names) != "[repo0 repo1]" {
+		t.Errorf("expected repos: [repo0 repo1], got: %v", names)
+	}
+
+	var afters []string
+	for _, q := range ts.queries {
+		afters = append(afters, q.Get("after"))
+	}
+
+	if fmt.Sprint(afters) != "[1 2]" {
+		t.Errorf("expected cursors: [1 2], got: %v", afters)
+	}
+
+	if err := state.Append(context.Background(), "", &Page{Number: 1, Size: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := testMap(context.Background(), s); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("expected error: %v, got: %v", ErrInvalidState, err)
+	}
+}
+
+func TestMapGraphQLRateLimit(t *testing.T) {
+	now := time.Now()
+
+	ts := newTestServer([]testStar{newTestStar(0, "owner", now)})
+	defer ts.Close()
+
+	var limited int32
+
+	graphql := ts.graphql(nil)
+	ts.mux.HandleFunc("/graphql", func(w http.ResponseWriter, r *http.Request) {
+		// NOTE: GraphQL API reports rate limiting with HTTP 200 status
+		if atomic.AddInt32(&limited, 1) == 1 {
+			w.Header().Set("Content-Type", "application/json")
+			// nolint:errcheck
+			w.Write([]byte(`{"errors": [{"type": "RATE_LIMITED", "message": "API rate limit exceeded"}]}`))
+			return
+		}
+		graphql(w, r)
+	})
+
+	s, err := NewScraper(ts.client(), GraphQL(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.rate.sleep = func(ctx context.Context, d time.Duration) error {
+		return nil
+	}
+
+	top, err := testMap(context.Background(), s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := repoNames(context.Background(), top)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fmt.Sprint(names) != "[repo0]" {
+		t.Errorf("expected repos: [repo0], got: %v", names)
+	}
+
+	// NOTE: rate limited query with no rate limit headers is retried after abuseWait
+	if state := s.RateState(); state.Waits != 1 || state.Waited < abuseWait-time.Second {
+		t.Errorf("expected 1 wait of %v, got: %d waits of %v", abuseWait, state.Waits, state.Waited)
+	}
+}
+
+func TestGraphQLPaging(t *testing.T) {
+	for _, tc := range []struct {
+		graphql bool
+		want    int
+	}{
+		{false, 200},
+		{true, graphqlMaxPaging},
+	} {
+		s, err := NewScraper(nil, GraphQL(tc.graphql), Paging(200))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if s.opts.Paging != tc.want {
+			t.Errorf("GraphQL %v: expected paging: %d, got: %d", tc.graphql, tc.want, s.opts.Paging)
+		}
+	}
+}
@@ -1,13 +1,47 @@
 package star
 
+import "time"
+
 // Options provides GitHub scraper options.
 type Options struct {
-	// User s GitHub username
-	User string
+	// Users are GitHub usernames
+	Users []string
 	// Paging size for GitHub API results
 	Paging int
 	// Workers for mapping repos
 	Workers int
+	// Checkpoints stores incremental scraping checkpoints
+	Checkpoints CheckpointStore
+	// Budget is the maximum number of API requests per rate limit window
+	Budget int
+	// RateHandler is called with every rate limit state update
+	RateHandler func(RateState)
+	// State stores the state of interrupted scrapes
+	State StateStore
+	// Resume resumes interrupted scrape
+	Resume bool
+	// RepoAttrs are extracted repository attributes
+	RepoAttrs []RepoAttr
+	// Languages fetches all repository languages
+	Languages bool
+	// Contributors is the number of top contributors mapped per repo
+	Contributors int
+	// Forks maps the upstream repos of starred forks
+	Forks bool
+	// Depth is the depth of stargazers crawling
+	Depth int
+	// FanOut are per-level crawling fan-out limits
+	FanOut []int
+	// GraphQL fetches starred repos from GitHub GraphQL API
+	GraphQL bool
+	// Partial continues scraping past failures
+	Partial bool
+	// OwnerNamespaces namespaces repos by their owners
+	OwnerNamespaces bool
+	// UIDs creates entity uids
+	UIDs UIDStrategy
+	// Filter selects mapped repos
+	Filter Filter
 }
 
 // Option is GitHub scraper option.
@@ -20,10 +54,22 @@ func Paging(p int) Option {
 	}
 }
 
-// User configures GitHub username.
+// User adds GitHub username to the configured users.
+// Empty username configures the authenticated user.
+// NOTE: User does not replace the users configured before, e.g. by Users;
+// the stars of all the configured users are scraped.
 func User(u string) Option {
 	return func(o *Options) {
-		o.User = u
+		o.Users = append(o.Users, u)
+	}
+}
+
+// Users configures GitHub usernames.
+// The stars of all the users are scraped concurrently into the same topology.
+// Only the authenticated user is scraped if no user is configured.
+func Users(u ...string) Option {
+	return func(o *Options) {
+		o.Users = append(o.Users, u...)
 	}
 }
 
@@ -33,3 +79,203 @@ func Workers(w int) Option {
 		o.Workers = w
 	}
 }
+
+// Incremental configures incremental scraping.
+// Only the repos starred after the checkpoint stored in c are scraped
+// and the checkpoint is updated when the scraping finishes.
+func Incremental(c CheckpointStore) Option {
+	return func(o *Options) {
+		o.Checkpoints = c
+	}
+}
+
+// Budget configures the maximum number of API requests per rate limit window.
+// API requests are paced to stay under the budget.
+func Budget(n int) Option {
+	return func(o *Options) {
+		o.Budget = n
+	}
+}
+
+// RateHandler configures GitHub API rate limit state handler.
+func RateHandler(f func(RateState)) Option {
+	return func(o *Options) {
+		o.RateHandler = f
+	}
+}
+
+// State configures the store of interrupted scrapes state.
+// Every completely mapped page is stored until the scrape finishes.
+func State(st StateStore) Option {
+	return func(o *Options) {
+		o.State = st
+	}
+}
+
+// Resume configures resuming interrupted scrape from the last completed page.
+// The repos of the completed pages are mapped from the stored state.
+// The stored state is discarded if Resume is not configured.
+func Resume(r bool) Option {
+	return func(o *Options) {
+		o.Resume = r
+	}
+}
+
+// RepoAttrs configures repository attributes extracted into repo entities.
+// Repo entities always have git_url attribute. They also have starred_at attribute
+// if a single user is scraped; otherwise the repo may be starred by several users
+// and starred_at is only set on their starred links.
+func RepoAttrs(a ...RepoAttr) Option {
+	return func(o *Options) {
+		o.RepoAttrs = append(o.RepoAttrs, a...)
+	}
+}
+
+// Languages configures fetching all repository languages.
+// Repos are linked to all their languages with the link weight
+// set to the language share of repository code bytes.
+// Only the primary repository language is linked by default.
+func Languages(l bool) Option {
+	return func(o *Options) {
+		o.Languages = l
+	}
+}
+
+// Contributors configures mapping the top n contributors of every repo.
+// Contributors are mapped as contributor entities and linked to repos with the link
+// weight set to the number of their contributions. The repo owner contributions are
+// linked from the owner entity instead. Contributors are not mapped by default.
+func Contributors(n int) Option {
+	return func(o *Options) {
+		o.Contributors = n
+	}
+}
+
+// Forks configures mapping the upstream repos of starred forks.
+// The parent and source repos of every fork are fetched, added to
+// the topology with their owners and linked from the fork.
+// Forks are mapped as unrelated repos by default.
+func Forks(f bool) Option {
+	return func(o *Options) {
+		o.Forks = f
+	}
+}
+
+// Crawl configures snowball crawling of stargazers up to the given depth.
+// At every level the stargazers of the repos discovered at the previous level
+// are sampled and their starred repos are mapped. fanout limits the number of
+// stargazers sampled per repo and the number of repos mapped per stargazer
+// at every level; the last limit applies to all the remaining levels.
+// Users and repos are crawled at most once. Crawling is disabled by default.
+func Crawl(depth int, fanout ...int) Option {
+	return func(o *Options) {
+		o.Depth = depth
+		o.FanOut = fanout
+	}
+}
+
+// GraphQL configures fetching starred repos from GitHub GraphQL API.
+// Repo topics, languages with their sizes and licenses are fetched
+// with the starred repos in a single query per page, so no extra
+// API requests are needed to map all the repo languages.
+// The topology is the same as the one mapped from GitHub REST API.
+// NOTE: GraphQL API pages can't be larger than 100 repos,
+// so larger Paging is reduced to 100 when GraphQL is configured.
+func GraphQL(g bool) Option {
+	return func(o *Options) {
+		o.GraphQL = g
+	}
+}
+
+// Partial configures partial scraping.
+// Scraping continues past failed users, pages and repos and
+// the partial topology is returned with MapError listing up to MaxFailures failures.
+// Canceled scraping returns the partial topology with MapError, too.
+// By default scraping stops on the first failure.
+func Partial(p bool) Option {
+	return func(o *Options) {
+		o.Partial = p
+	}
+}
+
+// OwnerNamespaces configures namespacing repos by their owners.
+// Repo entities are placed in the namespace named after the lowercase
+// owner login instead of the global namespace.
+func OwnerNamespaces(n bool) Option {
+	return func(o *Options) {
+		o.OwnerNamespaces = n
+	}
+}
+
+// UIDs configures the uid strategy used for all the mapped entities.
+// DefaultUIDs are used by default.
+func UIDs(u UIDStrategy) Option {
+	return func(o *Options) {
+		o.UIDs = u
+	}
+}
+
+// IncludeLanguages configures mapping only the repos with the given primary languages.
+func IncludeLanguages(l ...string) Option {
+	return func(o *Options) {
+		o.Filter.Languages = append(o.Filter.Languages, l...)
+	}
+}
+
+// ExcludeLanguages configures skipping the repos with the given primary languages.
+func ExcludeLanguages(l ...string) Option {
+	return func(o *Options) {
+		o.Filter.ExcludeLanguages = append(o.Filter.ExcludeLanguages, l...)
+	}
+}
+
+// IncludeTopics configures mapping only the repos with any of the given topics.
+func IncludeTopics(t ...string) Option {
+	return func(o *Options) {
+		o.Filter.Topics = append(o.Filter.Topics, t...)
+	}
+}
+
+// ExcludeTopics configures skipping the repos with any of the given topics.
+func ExcludeTopics(t ...string) Option {
+	return func(o *Options) {
+		o.Filter.ExcludeTopics = append(o.Filter.ExcludeTopics, t...)
+	}
+}
+
+// IncludeOwners configures mapping only the repos owned by the given users or organizations.
+func IncludeOwners(l ...string) Option {
+	return func(o *Options) {
+		o.Filter.Owners = append(o.Filter.Owners, l...)
+	}
+}
+
+// ExcludeOwners configures skipping the repos owned by the given users or organizations.
+func ExcludeOwners(l ...string) Option {
+	return func(o *Options) {
+		o.Filter.ExcludeOwners = append(o.Filter.ExcludeOwners, l...)
+	}
+}
+
+// StarredBetween configures mapping only the repos starred at or after since and before until.
+// Zero time leaves the range unbounded.
+func StarredBetween(since, until time.Time) Option {
+	return func(o *Options) {
+		o.Filter.Since = since
+		o.Filter.Until = until
+	}
+}
+
+// MinStars configures mapping only the repos with at least n stargazers.
+func MinStars(n int) Option {
+	return func(o *Options) {
+		o.Filter.MinStars = n
+	}
+}
+
+// Archived configures mapping only the repos with the given archived status.
+func Archived(a bool) Option {
+	return func(o *Options) {
+		o.Filter.Archived = &a
+	}
+}
@@ -0,0 +1,109 @@
+package star
+
+import (
+	"context"
+	"sync"
+
+	"github.com/google/go-github/v32/github"
+)
+
+// MaxFailures is the maximum number of failures collected by partial scraping.
+const MaxFailures = 1000
+
+// failures collects the failures of partial scraping.
+// NOTE: it is safe to call failures methods on nil failures.
+type failures struct {
+	// list are the collected failures
+	list []*Failure
+	// omitted is the number of failures which have not been collected
+	omitted int
+	// users are the users whose scraping failed
+	users map[string]bool
+	// mu synchronizes access to failures
+	mu *sync.Mutex
+}
+
+// newFailures creates a new empty failures collector and returns it.
+func newFailures() *failures {
+	return &failures{
+		users: make(map[string]bool),
+		mu:    &sync.Mutex{},
+	}
+}
+
+// add collects failure f unless ctx is done.
+// Failures are only counted once MaxFailures failures have been collected.
+// It returns false if failures are not collected, i.e. scraping is not partial.
+// NOTE: failures caused by cancellation are not collected
+// since cancellation is reported by MapError itself.
+func (fs *failures) add(ctx context.Context, f *Failure) bool {
+	if fs == nil {
+		return false
+	}
+
+	if ctx.Err() != nil {
+		return true
+	}
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if f.User != "" {
+		fs.users[f.User] = true
+	}
+
+	if len(fs.list) >= MaxFailures {
+		fs.omitted++
+		return true
+	}
+
+	fs.list = append(fs.list, f)
+
+	return true
+}
+
+// canceled returns ctx error if ctx is done and scraping is not partial.
+// NOTE: cancellation of partial scraping is reported by MapError.
+func (fs *failures) canceled(ctx context.Context) error {
+	if fs != nil {
+		return nil
+	}
+
+	return ctx.Err()
+}
+
+// failed returns true if scraping of the given user failed.
+func (fs *failures) failed(user string) bool {
+	if fs == nil {
+		return false
+	}
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	return fs.users[user]
+}
+
+// err returns MapError if any failure has been collected or ctx is done.
+func (fs *failures) err(ctx context.Context) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if len(fs.list) == 0 && ctx.Err() == nil {
+		return nil
+	}
+
+	list := make([]*Failure, len(fs.list))
+	copy(list, fs.list)
+
+	return &MapError{
+		Failures: list,
+		Omitted:  fs.omitted,
+		Err:      ctx.Err(),
+	}
+}
+
+// repoName returns the full name of GH repo.
+func repoName(repo *github.Repository) string {
+	return repo.GetOwner().GetLogin() + "/" + repo.GetName()
+}
@@ -0,0 +1,186 @@
+package star
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestMapPartial(t *testing.T) {
+	now := time.Now()
+
+	var stars []testStar
+	for i := 0; i < 4; i++ {
+		stars = append(stars, newTestStar(i, "owner", now))
+	}
+	// NOTE: fork upstream is not served, so mapping repo0 fails
+	stars[0].Repo.Fork = true
+
+	ts := newTestServer(stars)
+	defer ts.Close()
+
+	ts.users["alice"] = []testStar{newTestStar(5, "owner", now)}
+
+	ts.fail = func(w http.ResponseWriter, r *http.Request) bool {
+		if r.URL.Path != "/user/starred" || r.URL.Query().Get("page") != "3" {
+			return false
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		return true
+	}
+
+	state := NewMemState()
+
+	s, err := NewScraper(ts.client(), Users("", "alice"), Paging(1), Forks(true), State(state), Partial(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	top, err := testMap(context.Background(), s)
+
+	var mapErr *MapError
+	if !errors.As(err, &mapErr) {
+		t.Fatalf("expected MapError, got: %v", err)
+	}
+
+	if errors.Is(err, context.Canceled) {
+		t.Errorf("unexpected cancellation: %v", err)
+	}
+
+	var failures []string
+	for _, f := range mapErr.Failures {
+		failures = append(failures, fmt.Sprintf("%s/%d/%s", f.User, f.Page, f.Repo))
+	}
+	sort.Strings(failures)
+
+	if want := "[me/1/owner/repo0 me/3/]"; fmt.Sprint(failures) != want {
+		t.Errorf("expected failures: %s, got: %v", want, failures)
+	}
+
+	names, err := repoNames(context.Background(), top)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "[repo0 repo1 repo5]"; fmt.Sprint(names) != want {
+		t.Errorf("expected repos: %s, got: %v", want, names)
+	}
+
+	// NOTE: the failed page is not completed, so the following pages can't be stored
+	if pages, _ := state.Load(context.Background(), ""); len(pages) != 0 {
+		t.Errorf("expected no stored pages, got: %d", len(pages))
+	}
+}
+
+func TestMapPartialCancel(t *testing.T) {
+	ts := newTestServer([]testStar{newTestStar(0, "owner", time.Now())})
+	defer ts.Close()
+
+	s, err := NewScraper(ts.client(), Partial(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	top, err := testMap(ctx, s)
+	if top == nil {
+		t.Fatal("expected partial topology")
+	}
+
+	var mapErr *MapError
+	if !errors.As(err, &mapErr) || !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected canceled MapError, got: %v", err)
+	}
+
+	if len(mapErr.Failures) != 0 {
+		t.Errorf("expected no failures, got: %v", mapErr.Failures)
+	}
+}
+
+// cancelState is state store which cancels scraping once a page is completed.
+type cancelState struct {
+	*MemState
+	cancel context.CancelFunc
+}
+
+func (c *cancelState) Append(ctx context.Context, user string, p *Page) error {
+	c.cancel()
+	return c.MemState.Append(ctx, user, p)
+}
+
+func TestMapCancel(t *testing.T) {
+	ts := newTestServer([]testStar{newTestStar(0, "owner", time.Now())})
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// NOTE: scraping is canceled after the only page has been fetched
+	state := &cancelState{MemState: NewMemState(), cancel: cancel}
+
+	s, err := NewScraper(ts.client(), State(state))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := testMap(ctx, s); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected error: %v, got: %v", context.Canceled, err)
+	}
+
+	// NOTE: the state of canceled scraping is not cleared
+	if pages, _ := state.Load(context.Background(), ""); len(pages) != 1 {
+		t.Errorf("expected stored pages: %d, got: %d", 1, len(pages))
+	}
+}
+
+func TestFailuresLimit(t *testing.T) {
+	fs := newFailures()
+
+	for i := 0; i < MaxFailures+2; i++ {
+		fs.add(context.Background(), &Failure{Repo: fmt.Sprintf("owner/repo%d", i), Err: errors.New("boom")})
+	}
+	fs.add(context.Background(), &Failure{User: "alice", Err: errors.New("boom")})
+
+	var mapErr *MapError
+	if err := fs.err(context.Background()); !errors.As(err, &mapErr) {
+		t.Fatalf("expected MapError, got: %v", err)
+	}
+
+	if len(mapErr.Failures) != MaxFailures || mapErr.Omitted != 3 {
+		t.Errorf("expected %d failures and %d omitted, got: %d and %d", MaxFailures, 3, len(mapErr.Failures), mapErr.Omitted)
+	}
+
+	// NOTE: omitted user failures are still recorded
+	if !fs.failed("alice") {
+		t.Errorf("expected failed user: alice")
+	}
+}
+
+func TestFailureError(t *testing.T) {
+	err := errors.New("boom")
+
+	testCases := []struct {
+		failure *Failure
+		want    string
+	}{
+		{&Failure{Err: err}, "boom"},
+		{&Failure{User: "me", Err: err}, "user me: boom"},
+		{&Failure{User: "me", Page: 2, Repo: "owner/repo", Err: err}, "user me page 2 repo owner/repo: boom"},
+	}
+
+	for _, tc := range testCases {
+		if got := tc.failure.Error(); got != tc.want {
+			t.Errorf("expected: %q, got: %q", tc.want, got)
+		}
+
+		if !errors.Is(tc.failure, err) {
+			t.Errorf("expected %v to wrap %v", tc.failure, err)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package star
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v32/github"
+)
+
+const (
+	// abuseWait is default wait after hitting abuse rate limit
+	// when GitHub API does not return Retry-After header
+	abuseWait = time.Minute
+)
+
+// RateState is GitHub API rate limit state.
+type RateState struct {
+	// Limit is the number of requests per hour
+	Limit int
+	// Remaining is the number of remaining requests in the current rate limit window
+	Remaining int
+	// Reset is the time the current rate limit window resets
+	Reset time.Time
+	// Requests is the number of API requests made by scraper
+	Requests int
+	// Waits is the number of times scraper waited for rate limit
+	Waits int
+	// Waited is the total time scraper waited for rate limit
+	Waited time.Duration
+}
+
+// limiter paces GitHub API requests and waits when the rate limit is hit.
+type limiter struct {
+	// budget is the maximum number of requests per rate limit window
+	budget int
+	// handler is called with every rate limit state update
+	handler func(RateState)
+	// state is the rate limit state
+	state RateState
+	// mu synchronizes access to state
+	mu *sync.Mutex
+	// sleep waits for the given duration or until ctx is done
+	sleep func(context.Context, time.Duration) error
+}
+
+// newLimiter creates a new limiter and returns it.
+func newLimiter(budget int, handler func(RateState)) *limiter {
+	return &limiter{
+		budget:  budget,
+		handler: handler,
+		mu:      &sync.Mutex{},
+		sleep:   sleep,
+	}
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// State returns the current rate limit state.
+func (l *limiter) State() RateState {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.state
+}
+
+// update updates the rate limit state and reports it.
+func (l *limiter) update(f func(*RateState)) {
+	l.mu.Lock()
+	f(&l.state)
+	state := l.state
+	l.mu.Unlock()
+
+	if l.handler != nil {
+		l.handler(state)
+	}
+}
+
+// delay returns the time to wait before the next request to stay under the budget.
+// The remaining budget is spread evenly until the rate limit window resets.
+func (l *limiter) delay() time.Duration {
+	if l.budget <= 0 {
+		return 0
+	}
+
+	state := l.State()
+	if state.Limit == 0 {
+		return 0
+	}
+
+	until := time.Until(state.Reset)
+	if until <= 0 {
+		return 0
+	}
+
+	// NOTE: requests over the budget are reserved for other API clients
+	available := state.Remaining - (state.Limit - l.budget)
+	if available <= 0 {
+		return until
+	}
+
+	return until / time.Duration(available)
+}
+
+// do calls f, which makes a single GitHub API request, until it succeeds
+// or fails with error other than rate limit error.
+// The requests are paced to stay under the budget if it is configured.
+// It returns error if ctx is done while waiting.
+func (l *limiter) do(ctx context.Context, f func() (*github.Response, error)) error {
+	for {
+		if err := l.sleep(ctx, l.delay()); err != nil {
+			return err
+		}
+
+		resp, err := f()
+
+		l.update(func(s *RateState) {
+			s.Requests++
+			if resp != nil && resp.Rate.Limit > 0 {
+				s.Limit = resp.Rate.Limit
+				s.Remaining = resp.Rate.Remaining
+				s.Reset = resp.Rate.Reset.Time
+			}
+		})
+
+		wait, ok := retryAfter(err)
+		if !ok {
+			return err
+		}
+
+		l.update(func(s *RateState) {
+			s.Waits++
+			s.Waited += wait
+		})
+
+		if err := l.sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// retryAfter returns the time to wait before retrying the request which failed with err.
+// It returns false if err is not a rate limit error.
+func retryAfter(err error) (time.Duration, bool) {
+	var rateErr *github.RateLimitError
+	if errors.As(err, &rateErr) {
+		wait := time.Until(rateErr.Rate.Reset.Time)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+
+	var abuseErr *github.AbuseRateLimitError
+	if errors.As(err, &abuseErr) {
+		if abuseErr.RetryAfter != nil {
+			return *abuseErr.RetryAfter, true
+		}
+		return abuseWait, true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,159 @@
+package star
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestMapRateLimit(t *testing.T) {
+	now := time.Now()
+
+	stars := []testStar{
+		newTestStar(0, "owner", now),
+		newTestStar(1, "owner", now),
+	}
+
+	testCases := []struct {
+		name string
+		fail func(w http.ResponseWriter, r *http.Request)
+		wait time.Duration
+	}{
+		{
+			name: "RateLimit",
+			fail: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-RateLimit-Limit", "60")
+				w.Header().Set("X-RateLimit-Remaining", "0")
+				w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(now.Add(-time.Second).Unix(), 10))
+				w.WriteHeader(http.StatusForbidden)
+				// nolint:errcheck
+				w.Write([]byte(`{"message": "API rate limit exceeded"}`))
+			},
+			wait: 0,
+		},
+		{
+			name: "AbuseRateLimit",
+			fail: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Retry-After", "3")
+				w.WriteHeader(http.StatusForbidden)
+				// nolint:errcheck
+				w.Write([]byte(`{"message": "abuse", "documentation_url": "https://developer.github.com/v3/#abuse-rate-limits"}`))
+			},
+			wait: 3 * time.Second,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ts := newTestServer(stars)
+			defer ts.Close()
+
+			failed := false
+			ts.fail = func(w http.ResponseWriter, r *http.Request) bool {
+				if failed {
+					return false
+				}
+				failed = true
+				tc.fail(w, r)
+				return true
+			}
+
+			var states []RateState
+
+			s, err := NewScraper(ts.client(), Paging(1), RateHandler(func(s RateState) {
+				states = append(states, s)
+			}))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var slept []time.Duration
+			s.rate.sleep = func(ctx context.Context, d time.Duration) error {
+				slept = append(slept, d)
+				return nil
+			}
+
+			top, err := testMap(context.Background(), s)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			names, err := repoNames(context.Background(), top)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(names) != len(stars) {
+				t.Errorf("expected repos: %d, got: %d", len(stars), len(names))
+			}
+
+			state := s.RateState()
+			if state.Waits != 1 || state.Waited != tc.wait {
+				t.Errorf("expected 1 wait of %v, got: %d waits of %v", tc.wait, state.Waits, state.Waited)
+			}
+
+			// NOTE: the authenticated user is fetched before the starred repos
+			if state.Requests != 4 {
+				t.Errorf("expected requests: %d, got: %d", 4, state.Requests)
+			}
+
+			if len(states) == 0 {
+				t.Errorf("expected rate state updates")
+			}
+		})
+	}
+}
+
+func TestMapRateLimitCancel(t *testing.T) {
+	ts := newTestServer([]testStar{newTestStar(0, "owner", time.Now())})
+	defer ts.Close()
+
+	ts.fail = func(w http.ResponseWriter, r *http.Request) bool {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusForbidden)
+		// nolint:errcheck
+		w.Write([]byte(`{"message": "abuse", "documentation_url": "https://developer.github.com/v3/#abuse-rate-limits"}`))
+		return true
+	}
+
+	s, err := NewScraper(ts.client())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := testMap(ctx, s); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got: %v, want: %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestLimiterDelay(t *testing.T) {
+	reset := time.Now().Add(time.Hour)
+
+	testCases := []struct {
+		name   string
+		budget int
+		min    time.Duration
+		max    time.Duration
+	}{
+		{"NoBudget", 0, 0, 0},
+		{"Pace", 50, 5 * time.Minute, 6 * time.Minute},
+		{"Exhausted", 30, 59 * time.Minute, time.Hour},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			l := newLimiter(tc.budget, nil)
+			l.state = RateState{Limit: 100, Remaining: 60, Reset: reset}
+
+			if d := l.delay(); d < tc.min || d > tc.max {
+				t.Errorf("expected delay between %v and %v, got: %v", tc.min, tc.max, d)
+			}
+		})
+	}
+}
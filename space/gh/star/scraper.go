@@ -2,8 +2,13 @@ package star
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/google/go-github/v32/github"
 	"github.com/milosgajdos/netscrape/pkg/attrs"
@@ -30,6 +35,10 @@ type scraper struct {
 	gh *github.Client
 	// opts are scraper options
 	opts Options
+	// rate limits API requests
+	rate *limiter
+	// mu synchronizes merging of repo entities
+	mu *sync.Mutex
 }
 
 // NewScraper creates a new GitHub star repository scraper and returns it.
@@ -47,12 +56,27 @@ func NewScraper(gh *github.Client, opts ...Option) (*scraper, error) {
 		copts.Paging = paging
 	}
 
+	if copts.GraphQL && copts.Paging > graphqlMaxPaging {
+		copts.Paging = graphqlMaxPaging
+	}
+
+	if copts.UIDs == nil {
+		copts.UIDs = DefaultUIDs{}
+	}
+
 	return &scraper{
 		gh:   gh,
 		opts: copts,
+		rate: newLimiter(copts.Budget, copts.RateHandler),
+		mu:   &sync.Mutex{},
 	}, nil
 }
 
+// RateState returns GitHub API rate limit state.
+func (s *scraper) RateState() RateState {
+	return s.rate.State()
+}
+
 // params groups resource parameters
 type params struct {
 	name    string
@@ -64,6 +88,7 @@ type params struct {
 
 func defaultParams() []params {
 	return []params{
+		{name: stargazerRes, group: stargazerGroup, version: version, kind: resKind, ns: true},
 		{name: ownerRes, group: ownerGroup, version: version, kind: resKind, ns: true},
 		{name: repoRes, group: repoGroup, version: version, kind: resKind, ns: true},
 		{name: topicRes, group: topicGroup, version: version, kind: resKind, ns: true},
@@ -71,6 +96,17 @@ func defaultParams() []params {
 	}
 }
 
+// params returns parameters of all the resources mapped by scraper.
+func (s *scraper) params() []params {
+	px := defaultParams()
+
+	if s.opts.Contributors > 0 {
+		px = append(px, params{name: contributorRes, group: contributorGroup, version: version, kind: resKind, ns: true})
+	}
+
+	return px
+}
+
 // Plan creates a new space.Plan and adds GitHub stars resources to it.
 func (s *scraper) Plan(ctx context.Context, o space.Origin) (space.Plan, error) {
 	plan, err := plan.New(o)
@@ -78,9 +114,7 @@ func (s *scraper) Plan(ctx context.Context, o space.Origin) (space.Plan, error)
 		return nil, err
 	}
 
-	params := defaultParams()
-
-	for _, p := range params {
+	for _, p := range s.params() {
 		r, err := resource.New(p.name, p.group, p.version, p.kind, p.ns)
 		if err != nil {
 			return nil, err
@@ -94,47 +128,121 @@ func (s *scraper) Plan(ctx context.Context, o space.Origin) (space.Plan, error)
 	return plan, nil
 }
 
-// fetchRepos fetches GitHub repos into reposChan.
+// newStars returns the repos starred after since.
+// It returns true if any of the repos was starred at or before since.
+func newStars(repos []*github.StarredRepository, since time.Time) ([]*github.StarredRepository, bool) {
+	for i, repo := range repos {
+		if !repo.GetStarredAt().After(since) {
+			return repos[:i], true
+		}
+	}
+
+	return repos, false
+}
+
+// cursor is repos fetching cursor.
+type cursor struct {
+	// user is the user whose starred repos are fetched
+	user string
+	// page is the number of the first fetched page
+	page int
+	// after is GraphQL API cursor of the page preceding the first fetched page
+	after string
+	// since is incremental scraping checkpoint
+	since time.Time
+	// latest is the time of the most recent star
+	latest time.Time
+}
+
+// fetchRepos fetches pages of GitHub repos into pages starting with the cursor page.
 // Fetching can be stopped by closing done channel.
-func (s *scraper) fetchRepos(ctx context.Context, reposChan chan<- []*github.StarredRepository, done <-chan struct{}) error {
-	defer close(reposChan)
+// In incremental mode repos are fetched from the most recently starred
+// until a repo starred at or before the cursor checkpoint is found;
+// the time of the most recent star is recorded in the cursor.
+func (s *scraper) fetchRepos(ctx context.Context, pages chan<- *Page, done <-chan struct{}, c *cursor) error {
+	defer close(pages)
 
-	opts := &github.ActivityListStarredOptions{
-		ListOptions: github.ListOptions{PerPage: s.opts.Paging},
+	fetch := s.fetchPage
+	if s.opts.GraphQL {
+		fetch = s.fetchGraphQLPage
 	}
 
 	for {
-		repos, resp, err := s.gh.Activity.ListStarred(ctx, s.opts.User, opts)
+		page, more, err := fetch(ctx, c)
 		if err != nil {
 			return err
 		}
 
+		var stop bool
+
+		if s.opts.Checkpoints != nil {
+			page.Stars, stop = newStars(page.Stars, c.since)
+			if len(page.Stars) > 0 && page.Stars[0].GetStarredAt().After(c.latest) {
+				c.latest = page.Stars[0].GetStarredAt().Time
+			}
+		}
+
 		select {
-		case reposChan <- repos:
+		case pages <- page:
 		case <-ctx.Done():
-			return nil
+			return ctx.Err()
 		case <-done:
 			return nil
 		}
 
-		if resp.NextPage == 0 {
+		if stop || !more {
 			break
 		}
-
-		opts.Page = resp.NextPage
 	}
 
 	return nil
 }
 
+// fetchPage fetches the page of starred repos at cursor c from GitHub REST API
+// and advances the cursor to the next page. It returns false if there is no next page.
+func (s *scraper) fetchPage(ctx context.Context, c *cursor) (*Page, bool, error) {
+	opts := &github.ActivityListStarredOptions{
+		ListOptions: github.ListOptions{PerPage: s.opts.Paging, Page: c.page},
+	}
+
+	if s.opts.Checkpoints != nil {
+		opts.Sort = "created"
+		opts.Direction = "desc"
+	}
+
+	var (
+		repos []*github.StarredRepository
+		resp  *github.Response
+	)
+
+	err := s.rate.do(ctx, func() (*github.Response, error) {
+		var err error
+		repos, resp, err = s.gh.Activity.ListStarred(ctx, c.user, opts)
+		return resp, err
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	page := &Page{
+		Number: c.page,
+		Size:   s.opts.Paging,
+		Stars:  repos,
+	}
+
+	c.page = resp.NextPage
+
+	return page, resp.NextPage != 0, nil
+}
+
 // addEntities creates space entities from r with given names in namespace ns and adds them to top.
 func (s *scraper) addEntities(ctx context.Context, top space.Top, names []string, ns string, r space.Resource) ([]space.Entity, error) {
 	entities := make([]space.Entity, len(names))
 
 	for i, name := range names {
-		// NOTE: we are setting the uid to the name of the entity
+		// NOTE: we are deriving the uid from the name of the entity
 		// this is so we avoid duplicating topics with the same name
-		uid, err := uuid.NewFromString(strings.ToLower(name + "-" + r.Name()))
+		uid, err := s.opts.UIDs.UID(r.Name(), strings.ToLower(name))
 		if err != nil {
 			return nil, err
 		}
@@ -152,110 +260,519 @@ func (s *scraper) addEntities(ctx context.Context, top space.Top, names []string
 	return entities, nil
 }
 
-// mapRepos reads GH repos from respoChan and adds them to topology top.
-// mapRepos also adds repo owner, topics and langs to top, too.
-// Before the repos are added to top, several links are created:
-// * owner of the repo is linked to the repo
-// * repo topics and langs are added to top
-// * repo is linked to all the topics and langs
-func (s *scraper) mapRepos(ctx context.Context, reposChan <-chan []*github.StarredRepository, top space.Top, resMap map[string]space.Resource) error {
-	for repos := range reposChan {
-		// NOTE: we are only iterating over the repos resources
-		// since owners, topics and langs are merely adjacent nodes of repos
-		// and do not have any API endpoint for querying them further
-		for _, repo := range repos {
-			a, err := attrs.New()
-			if err != nil {
-				return err
-			}
-			a.Set("starred_at", repo.StarredAt.Format(dateTime))
-			a.Set("git_url", repo.Repository.GetURL())
+// mapRepos reads pages of GH repos starred by stargazer from pages and adds them to topology top.
+// Every completely mapped page is recorded in prog and every mapped repo is added to crawling frontier f.
+// If scraping is partial, failed repos are collected in fails and the remaining repos are still mapped.
+func (s *scraper) mapRepos(ctx context.Context, pages <-chan *Page, stargazer space.Entity, top space.Top, resMap map[string]space.Resource, prog *progress, f *frontier, fails *failures) error {
+	for page := range pages {
+		ok, err := s.mapPage(ctx, page, stargazer, top, resMap, f, fails)
+		if err != nil {
+			return err
+		}
 
-			uid, err := uuid.NewFromString(*repo.Repository.NodeID)
-			if err != nil {
-				return err
-			}
+		// NOTE: pages with failed repos are not completed, so they are remapped when resumed
+		if !ok {
+			continue
+		}
 
-			repoEnt, err := entity.New(*repo.Repository.Name, ns, resMap[repoRes], entity.WithUID(uid), entity.WithAttrs(a))
-			if err != nil {
+		if err := prog.complete(ctx, page); err != nil {
+			if !fails.add(ctx, &Failure{User: stargazer.Name(), Page: page.Number, Err: err}) {
 				return err
 			}
+		}
+	}
 
-			if err := top.Add(ctx, repoEnt); err != nil {
-				return err
+	return nil
+}
+
+// mapPage adds GH repos of page starred by stargazer which match the configured filter to topology top.
+// Every mapped repo is added to crawling frontier f.
+// If scraping is partial, failed repos are collected in fails and the remaining repos are still mapped.
+// It returns false if any of the repos failed.
+func (s *scraper) mapPage(ctx context.Context, page *Page, stargazer space.Entity, top space.Top, resMap map[string]space.Resource, f *frontier, fails *failures) (bool, error) {
+	ok := true
+
+	// NOTE: we are only iterating over the repos resources
+	// since owners, topics and langs are merely adjacent nodes of repos
+	// and do not have any API endpoint for querying them further
+	for _, repo := range page.Stars {
+		if !s.opts.Filter.match(repo) {
+			continue
+		}
+
+		if err := s.mapRepo(ctx, stargazer, repo, page.Languages[repo.GetRepository().GetNodeID()], top, resMap); err != nil {
+			failure := &Failure{
+				User: stargazer.Name(),
+				Page: page.Number,
+				Repo: repoName(repo.GetRepository()),
+				Err:  err,
 			}
 
-			owner := *repo.Repository.Owner.Login
-			if repo.Repository.Organization != nil {
-				owner = *repo.Repository.Organization.Login
+			if !fails.add(ctx, failure) {
+				return false, err
 			}
+			ok = false
+			continue
+		}
+		f.add(repo.Repository)
+	}
+
+	return ok, nil
+}
+
+// mapRepo adds GH repo starred by stargazer to topology top.
+// mapRepo also adds repo owner, topics and langs to top, too.
+// If sizes is not nil, it is used as prefetched repo language sizes.
+// Before the repo is added to top, several links are created:
+// * stargazer is linked to the repo
+// * owner of the repo is linked to the repo
+// * repo topics and langs are added to top
+// * repo is linked to all the topics and langs
+// * if Forks is configured, forked repo is linked to its upstream repos
+func (s *scraper) mapRepo(ctx context.Context, stargazer space.Entity, repo *github.StarredRepository, sizes map[string]int, top space.Top, resMap map[string]space.Resource) error {
+	repoEnt, err := s.repoEntity(repo.Repository, resMap[repoRes])
+	if err != nil {
+		return err
+	}
 
-			ownerUID, err := uuid.NewFromString(owner + "-" + ownerRes)
+	// NOTE: the repos starred by several users are shared
+	// so starred_at is only set on the links of every stargazer
+	if len(s.users()) == 1 {
+		repoEnt.Attrs().Set("starred_at", repo.StarredAt.Format(dateTime))
+	}
+
+	if err := s.mergeEntity(ctx, top, repoEnt); err != nil {
+		return err
+	}
+
+	a, err := attrs.New()
+	if err != nil {
+		return err
+	}
+	a.Set(attrs.Relation, stargazerRel)
+	a.Set(attrs.DOTLabel, stargazerRel)
+	a.Set("starred_at", repo.StarredAt.Format(dateTime))
+
+	if err := top.Link(ctx, stargazer.UID(), repoEnt.UID(), space.WithAttrs(a), space.WithMerge(true)); err != nil {
+		return err
+	}
+
+	ownerEnt, err := s.mapOwner(ctx, repo.Repository, repoEnt, top, resMap)
+	if err != nil {
+		return err
+	}
+
+	if s.opts.Forks && repo.Repository.GetFork() {
+		if err := s.mapUpstream(ctx, repo.Repository, repoEnt, top, resMap); err != nil {
+			return err
+		}
+	}
+
+	if s.opts.Contributors > 0 {
+		if err := s.mapContributors(ctx, repo.Repository, repoEnt, ownerEnt, top, resMap); err != nil {
+			return err
+		}
+	}
+
+	topics, err := s.addEntities(ctx, top, repo.Repository.Topics, ns, resMap[topicRes])
+	if err != nil {
+		return err
+	}
+
+	for _, topic := range topics {
+		a, err := attrs.New()
+		if err != nil {
+			return err
+		}
+		a.Set(attrs.Relation, topicRel)
+		a.Set(attrs.DOTLabel, ownerRel)
+
+		if err := top.Link(ctx, repoEnt.UID(), topic.UID(), space.WithAttrs(a), space.WithMerge(true)); err != nil {
+			return err
+		}
+	}
+
+	names, weights, err := s.languages(ctx, repo.Repository, sizes)
+	if err != nil {
+		return err
+	}
+
+	langs, err := s.addEntities(ctx, top, names, ns, resMap[langRes])
+	if err != nil {
+		return err
+	}
+
+	for i, lang := range langs {
+		a, err := attrs.New()
+		if err != nil {
+			return err
+		}
+		a.Set(attrs.Relation, langRel)
+		a.Set(attrs.DOTLabel, ownerRel)
+
+		if weights != nil {
+			a.Set(attrs.Weight, strconv.FormatFloat(weights[i], 'f', -1, 64))
+		}
+
+		if err := top.Link(ctx, repoEnt.UID(), lang.UID(), space.WithAttrs(a), space.WithMerge(true)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// owner returns the login of GH repo owner.
+func owner(repo *github.Repository) string {
+	if repo.Organization != nil {
+		return repo.Organization.GetLogin()
+	}
+
+	return repo.GetOwner().GetLogin()
+}
+
+// namespace returns the namespace of GH repo entity.
+// Repos are namespaced by their owners if OwnerNamespaces is configured.
+func (s *scraper) namespace(repo *github.Repository) string {
+	if s.opts.OwnerNamespaces {
+		return strings.ToLower(owner(repo))
+	}
+
+	return ns
+}
+
+// repoEntity creates a new entity of resource r from GH repo.
+// The entity has git_url and the configured repo attributes.
+func (s *scraper) repoEntity(repo *github.Repository, r space.Resource) (space.Entity, error) {
+	a, err := attrs.New()
+	if err != nil {
+		return nil, err
+	}
+	a.Set("git_url", repo.GetURL())
+
+	for _, attr := range s.opts.RepoAttrs {
+		if v, ok := attr.value(repo); ok {
+			a.Set(string(attr), v)
+		}
+	}
+
+	uid, err := s.opts.UIDs.UID(repoRes, repo.GetNodeID())
+	if err != nil {
+		return nil, err
+	}
+
+	return entity.New(repo.GetName(), s.namespace(repo), r, entity.WithUID(uid), entity.WithAttrs(a))
+}
+
+// mergeEntity adds entity e to top.
+// If the entity has already been added, e.g. a repo as an upstream of a starred fork
+// or a user as an owner of another repo, the attributes of e are merged into the existing entity.
+func (s *scraper) mergeEntity(ctx context.Context, top space.Top, e space.Entity) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ents, err := top.Get(ctx, base.Build().Add(predicate.UID(e.UID())))
+	if err != nil {
+		return err
+	}
+
+	if len(ents) == 0 {
+		return top.Add(ctx, e)
+	}
+
+	for _, k := range e.Attrs().Keys() {
+		ents[0].Attrs().Set(k, e.Attrs().Get(k))
+	}
+
+	return nil
+}
+
+// mapOwner adds the owner of GH repo to top and links it to repoEnt.
+// The owner entity owner_type attribute is set to the GitHub account type.
+func (s *scraper) mapOwner(ctx context.Context, repo *github.Repository, repoEnt space.Entity, top space.Top, resMap map[string]space.Resource) (space.Entity, error) {
+	login, ownerType := owner(repo), repo.GetOwner().GetType()
+	if repo.Organization != nil {
+		ownerType = "Organization"
+	}
+
+	ownerUID, err := s.userUID(login)
+	if err != nil {
+		return nil, err
+	}
+
+	a, err := attrs.New()
+	if err != nil {
+		return nil, err
+	}
+
+	if ownerType != "" {
+		a.Set(ownerTypeAttr, ownerType)
+	}
+
+	ownerEnt, err := entity.New(login, ns, resMap[ownerRes], entity.WithUID(ownerUID), entity.WithAttrs(a))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.mergeEntity(ctx, top, ownerEnt); err != nil {
+		return nil, err
+	}
+
+	a, err = attrs.New()
+	if err != nil {
+		return nil, err
+	}
+	a.Set(attrs.Relation, ownerRel)
+	a.Set(attrs.DOTLabel, ownerRel)
+
+	if err := top.Link(ctx, ownerEnt.UID(), repoEnt.UID(), space.WithAttrs(a), space.WithMerge(true)); err != nil {
+		return nil, err
+	}
+
+	return ownerEnt, nil
+}
+
+// mapUpstream fetches the parent and source repos of the forked GH repo,
+// adds them and their owners to top and links repoEnt to them.
+// NOTE: source is the root of the fork network; it differs from
+// parent only if the fork has been created from another fork.
+func (s *scraper) mapUpstream(ctx context.Context, repo *github.Repository, repoEnt space.Entity, top space.Top, resMap map[string]space.Resource) error {
+	var fork *github.Repository
+
+	err := s.rate.do(ctx, func() (*github.Response, error) {
+		var (
+			resp *github.Response
+			err  error
+		)
+		fork, resp, err = s.gh.Repositories.Get(ctx, repo.GetOwner().GetLogin(), repo.GetName())
+		return resp, err
+	})
+	if err != nil {
+		return err
+	}
+
+	upstreams := map[string]*github.Repository{
+		upstreamParent: fork.GetParent(),
+		upstreamSource: fork.GetSource(),
+	}
+
+	for _, kind := range []string{upstreamParent, upstreamSource} {
+		upstream := upstreams[kind]
+		if upstream.GetNodeID() == "" {
+			continue
+		}
+
+		if kind == upstreamSource && upstream.GetNodeID() == fork.GetParent().GetNodeID() {
+			continue
+		}
+
+		upstreamEnt, err := s.repoEntity(upstream, resMap[repoRes])
+		if err != nil {
+			return err
+		}
+
+		if err := s.mergeEntity(ctx, top, upstreamEnt); err != nil {
+			return err
+		}
+
+		if _, err := s.mapOwner(ctx, upstream, upstreamEnt, top, resMap); err != nil {
+			return err
+		}
+
+		a, err := attrs.New()
+		if err != nil {
+			return err
+		}
+		a.Set(attrs.Relation, forkRel)
+		a.Set(attrs.DOTLabel, forkRel)
+		a.Set("upstream", kind)
+
+		if err := top.Link(ctx, repoEnt.UID(), upstreamEnt.UID(), space.WithAttrs(a), space.WithMerge(true)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// userUID returns the uid of GitHub repo owner with the given login.
+func (s *scraper) userUID(login string) (uuid.UID, error) {
+	return s.opts.UIDs.UID(ownerRes, login)
+}
+
+// contributorUID returns the uid of contributor with the given login.
+// NOTE: contributors do not share the uid with owners, so the contributor
+// entities do not depend on the order in which repos and their owners are mapped.
+func (s *scraper) contributorUID(login string) (uuid.UID, error) {
+	return s.opts.UIDs.UID(contributorRes, login)
+}
+
+// stargazerUID returns the uid of stargazer with the given login.
+// NOTE: stargazers do not share the uid with owners, so the links
+// of users who star their own repos don't overwrite each other.
+func (s *scraper) stargazerUID(login string) (uuid.UID, error) {
+	return s.opts.UIDs.UID(stargazerRes, login)
+}
+
+// mapStargazer adds the given user to top as a stargazer and returns it.
+// If user is empty the authenticated user is mapped.
+// The stargazer entity is created with the given options.
+func (s *scraper) mapStargazer(ctx context.Context, user string, top space.Top, resMap map[string]space.Resource, opts ...entity.Option) (space.Entity, error) {
+	login := user
+
+	if login == "" {
+		var u *github.User
+
+		err := s.rate.do(ctx, func() (*github.Response, error) {
+			var (
+				resp *github.Response
+				err  error
+			)
+			u, resp, err = s.gh.Users.Get(ctx, "")
+			return resp, err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		login = u.GetLogin()
+	}
+
+	uid, err := s.stargazerUID(login)
+	if err != nil {
+		return nil, err
+	}
+
+	ent, err := entity.New(login, ns, resMap[stargazerRes], append(opts, entity.WithUID(uid))...)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := top.Add(ctx, ent); err != nil {
+		return nil, err
+	}
+
+	return ent, nil
+}
+
+// mapContributors adds the top repo contributors to top and links them to repoEnt.
+// Contributor links are weighted by the number of contributions.
+// Contributors are mapped as contributor entities, except for the repo owner
+// whose contributions are merged into the existing owner link.
+func (s *scraper) mapContributors(ctx context.Context, repo *github.Repository, repoEnt, ownerEnt space.Entity, top space.Top, resMap map[string]space.Resource) error {
+	var contributors []*github.Contributor
+
+	opts := &github.ListContributorsOptions{
+		ListOptions: github.ListOptions{PerPage: s.opts.Contributors},
+	}
+
+	err := s.rate.do(ctx, func() (*github.Response, error) {
+		var (
+			resp *github.Response
+			err  error
+		)
+		contributors, resp, err = s.gh.Repositories.ListContributors(ctx, repo.GetOwner().GetLogin(), repo.GetName(), opts)
+		return resp, err
+	})
+	if err != nil {
+		return err
+	}
+
+	// NOTE: contributors are returned sorted by the number of contributions
+	if len(contributors) > s.opts.Contributors {
+		contributors = contributors[:s.opts.Contributors]
+	}
+
+	for _, c := range contributors {
+		// NOTE: anonymous contributors have no login
+		if c.GetLogin() == "" {
+			continue
+		}
+
+		// NOTE: the repo owner contributions are linked from the owner entity
+		ent, rel := ownerEnt, ownerRel
+
+		if c.GetLogin() != ownerEnt.Name() {
+			uid, err := s.contributorUID(c.GetLogin())
 			if err != nil {
 				return err
 			}
 
-			ownerEnt, err := entity.New(owner, ns, resMap[ownerRes], entity.WithUID(ownerUID))
+			ent, err = entity.New(c.GetLogin(), ns, resMap[contributorRes], entity.WithUID(uid))
 			if err != nil {
 				return err
 			}
 
-			if err := top.Add(ctx, ownerEnt); err != nil {
+			if err := s.mergeEntity(ctx, top, ent); err != nil {
 				return err
 			}
 
-			a, err = attrs.New()
-			if err != nil {
-				return err
-			}
-			a.Set(attrs.Relation, ownerRel)
-			a.Set(attrs.DOTLabel, ownerRel)
+			rel = contributorRel
+		}
 
-			if err := top.Link(ctx, ownerEnt.UID(), repoEnt.UID(), space.WithAttrs(a), space.WithMerge(true)); err != nil {
-				return err
-			}
+		a, err := attrs.New()
+		if err != nil {
+			return err
+		}
+		a.Set(attrs.Relation, rel)
+		a.Set(attrs.DOTLabel, rel)
+		a.Set(attrs.Weight, strconv.Itoa(c.GetContributions()))
+		a.Set("contributions", strconv.Itoa(c.GetContributions()))
 
-			topics, err := s.addEntities(ctx, top, repo.Repository.Topics, ns, resMap[topicRes])
-			if err != nil {
-				return err
-			}
+		if err := top.Link(ctx, ent.UID(), repoEnt.UID(), space.WithAttrs(a), space.WithMerge(true)); err != nil {
+			return err
+		}
+	}
 
-			for _, topic := range topics {
-				a, err := attrs.New()
-				if err != nil {
-					return err
-				}
-				a.Set(attrs.Relation, topicRel)
-				a.Set(attrs.DOTLabel, ownerRel)
+	return nil
+}
 
-				if err := top.Link(ctx, repoEnt.UID(), topic.UID(), space.WithAttrs(a), space.WithMerge(true)); err != nil {
-					return err
-				}
-			}
+// languages returns the names of repo languages.
+// If Languages is configured it returns all repo languages and
+// their shares of repo code bytes as weights, otherwise it only returns
+// the primary repo language without weights.
+// Repo languages are fetched unless their sizes are prefetched in langs.
+func (s *scraper) languages(ctx context.Context, repo *github.Repository, langs map[string]int) ([]string, []float64, error) {
+	if !s.opts.Languages {
+		if repo.Language == nil {
+			return nil, nil, nil
+		}
+		return []string{*repo.Language}, nil, nil
+	}
 
-			if repo.Repository.Language != nil {
-				langs, err := s.addEntities(ctx, top, []string{*repo.Repository.Language}, ns, resMap[langRes])
-				if err != nil {
-					return err
-				}
+	if langs == nil {
+		err := s.rate.do(ctx, func() (*github.Response, error) {
+			var (
+				resp *github.Response
+				err  error
+			)
+			langs, resp, err = s.gh.Repositories.ListLanguages(ctx, repo.GetOwner().GetLogin(), repo.GetName())
+			return resp, err
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
 
-				for _, lang := range langs {
-					a, err := attrs.New()
-					if err != nil {
-						return err
-					}
-					a.Set(attrs.Relation, langRel)
-					a.Set(attrs.DOTLabel, ownerRel)
-
-					if err := top.Link(ctx, repoEnt.UID(), lang.UID(), space.WithAttrs(a), space.WithMerge(true)); err != nil {
-						return err
-					}
-				}
-			}
+	names := make([]string, 0, len(langs))
+	total := 0
+
+	for name, bytes := range langs {
+		names = append(names, name)
+		total += bytes
+	}
+
+	sort.Strings(names)
+
+	weights := make([]float64, len(names))
+
+	for i, name := range names {
+		if total > 0 {
+			weights[i] = float64(langs[name]) / float64(total)
 		}
 	}
 
-	return nil
+	return names, weights, nil
 }
 
 // getResource queries p with query params from qp and returns the result.
@@ -293,61 +810,241 @@ func getResources(ctx context.Context, p space.Plan, qp ...params) (map[string]s
 	return rx, nil
 }
 
+// resume returns the pages completed by the interrupted scrape of the given user.
+// The stored state is cleared if resuming is not configured.
+func (s *scraper) resume(ctx context.Context, user string) ([]*Page, error) {
+	if !s.opts.Resume {
+		return nil, s.opts.State.Clear(ctx, user)
+	}
+
+	pages, err := s.opts.State.Load(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkPages(pages, s.opts.Paging); err != nil {
+		return nil, err
+	}
+
+	// NOTE: GraphQL API pages can only be resumed from a cursor
+	if s.opts.GraphQL && len(pages) > 0 && pages[len(pages)-1].Cursor == "" {
+		return nil, fmt.Errorf("page %d without cursor: %w", len(pages), ErrInvalidState)
+	}
+
+	return pages, nil
+}
+
+// users returns the configured users without duplicates.
+// The authenticated user is returned if no user is configured.
+func (s *scraper) users() []string {
+	if len(s.opts.Users) == 0 {
+		return []string{""}
+	}
+
+	seen := make(map[string]bool)
+	users := make([]string, 0, len(s.opts.Users))
+
+	for _, u := range s.opts.Users {
+		if !seen[u] {
+			seen[u] = true
+			users = append(users, u)
+		}
+	}
+
+	return users
+}
+
 // Map builds a map of GH stars space topology and returns it.
+// The stars of all the configured users are scraped concurrently into the same topology.
+// If crawling is configured, the stargazers of the mapped repos are crawled afterwards.
 // It returns error if any of the API calls fails with error.
+// If scraping is partial, the scraping continues past failed users, pages and repos
+// and the partial topology is returned with MapError which lists all the failures;
+// MapError is also returned if ctx is done before the scraping finishes.
+// Completely mapped pages are stored in the configured state store until
+// the scraping finishes, so interrupted scraping can be resumed.
 func (s *scraper) Map(ctx context.Context, p space.Plan) (space.Top, error) {
 	top, err := top.New()
 	if err != nil {
 		return nil, err
 	}
 
-	params := defaultParams()
+	if err := s.mapTop(ctx, p, top); err != nil {
+		var mapErr *MapError
+		if errors.As(err, &mapErr) {
+			return top, err
+		}
+		return nil, err
+	}
+
+	return top, nil
+}
 
-	rx, err := getResources(ctx, p, params...)
+// mapTop maps GH stars space topology into top.
+// It returns MapError if scraping is partial and it fails or ctx is done.
+func (s *scraper) mapTop(ctx context.Context, p space.Plan, top space.Top) error {
+	rx, err := getResources(ctx, p, s.params()...)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	reposChan := make(chan []*github.StarredRepository, s.opts.Workers)
-	errChan := make(chan error)
-	done := make(chan struct{})
+	var f *frontier
+	if s.opts.Depth > 0 {
+		f = newFrontier()
+	}
+
+	var fails *failures
+	if s.opts.Partial {
+		fails = newFailures()
+	}
+
+	users := s.users()
+	errChan := make(chan error, len(users))
+
+	for _, user := range users {
+		stargazer, err := s.mapStargazer(ctx, user, top, rx)
+		if err != nil {
+			if fails.add(ctx, &Failure{User: user, Err: err}) {
+				err = nil
+			}
+			errChan <- err
+			continue
+		}
+
+		go func(user string) {
+			err := s.mapUser(ctx, user, stargazer, top, rx, f, fails)
+			if err != nil && fails.add(ctx, &Failure{User: stargazer.Name(), Err: err}) {
+				err = nil
+			}
+			errChan <- err
+		}(user)
+	}
+
+	for range users {
+		if e := <-errChan; e != nil && err == nil {
+			err = e
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if f != nil && ctx.Err() == nil {
+		if err := s.crawl(ctx, f, top, rx, fails); err != nil {
+			return err
+		}
+	}
+
+	if fails != nil {
+		return fails.err(ctx)
+	}
+
+	return ctx.Err()
+}
 
-	var wg sync.WaitGroup
+// mapUser maps the repos starred by the given user into top.
+// The user and the mapped repos are recorded in crawling frontier f.
+// If scraping is partial, failed pages and repos are collected in fails.
+// The incremental scraping checkpoint and the state of the user
+// are only updated if the scraping of the user finishes without failures.
+func (s *scraper) mapUser(ctx context.Context, user string, stargazer space.Entity, top space.Top, rx map[string]space.Resource, f *frontier, fails *failures) error {
+	f.visit(stargazer.Name())
+
+	c := &cursor{user: user, page: 1}
+
+	var err error
+
+	if s.opts.Checkpoints != nil {
+		c.since, err = s.opts.Checkpoints.Get(ctx, user)
+		if err != nil {
+			return err
+		}
+	}
+
+	c.latest = c.since
+
+	var prog *progress
+
+	if s.opts.State != nil {
+		pages, err := s.resume(ctx, user)
+		if err != nil {
+			return err
+		}
+
+		for _, page := range pages {
+			if _, err := s.mapPage(ctx, page, stargazer, top, rx, f, fails); err != nil {
+				return err
+			}
+
+			for _, repo := range page.Stars {
+				if repo.GetStarredAt().After(c.latest) {
+					c.latest = repo.GetStarredAt().Time
+				}
+			}
+		}
+
+		c.page = len(pages) + 1
+		if len(pages) > 0 {
+			c.after = pages[len(pages)-1].Cursor
+		}
+		prog = newProgress(s.opts.State, user, c.page)
+	}
+
+	pages := make(chan *Page, s.opts.Workers)
+	// NOTE: errChan is buffered so every goroutine can report its result
+	errChan := make(chan error, s.opts.Workers+1)
+	done := make(chan struct{})
 
 	// launch repo processing workers
 	// these are building the graph
 	for i := 0; i < s.opts.Workers; i++ {
-		wg.Add(1)
-		go func(i int) {
-			defer wg.Done()
-			select {
-			case errChan <- s.mapRepos(ctx, reposChan, top, rx):
-			case <-done:
-			case <-ctx.Done():
-			}
-		}(i)
+		go func() {
+			errChan <- s.mapRepos(ctx, pages, stargazer, top, rx, prog, f, fails)
+		}()
 	}
 
-	wg.Add(1)
 	go func() {
-		defer wg.Done()
-		select {
-		case errChan <- s.fetchRepos(ctx, reposChan, done):
-		case <-ctx.Done():
-		}
+		errChan <- s.fetchRepos(ctx, pages, done, c)
 	}()
 
-	select {
-	case err = <-errChan:
-	case <-ctx.Done():
+	// NOTE: fetching is stopped on the first error,
+	// but the remaining workers still drain pages
+	for i := 0; i < s.opts.Workers+1; i++ {
+		if e := <-errChan; e != nil && err == nil {
+			err = e
+			close(done)
+		}
 	}
 
-	close(done)
-	wg.Wait()
-
 	if err != nil {
-		return nil, err
+		// NOTE: only fetching fails if scraping is partial
+		if !fails.add(ctx, &Failure{User: stargazer.Name(), Page: c.page, Err: err}) {
+			return err
+		}
 	}
 
-	return top, nil
+	// NOTE: scraping is not finished if ctx is done
+	if ctx.Err() != nil {
+		return fails.canceled(ctx)
+	}
+
+	if fails.failed(stargazer.Name()) {
+		return nil
+	}
+
+	// NOTE: checkpoint is only updated if all the new stars have been mapped
+	if s.opts.Checkpoints != nil && c.latest.After(c.since) {
+		if err := s.opts.Checkpoints.Put(ctx, user, c.latest); err != nil {
+			return err
+		}
+	}
+
+	if s.opts.State != nil {
+		if err := s.opts.State.Clear(ctx, user); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
@@ -0,0 +1,714 @@
+package star
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v32/github"
+	"github.com/milosgajdos/netscrape/pkg/attrs"
+	"github.com/milosgajdos/netscrape/pkg/query/base"
+	"github.com/milosgajdos/netscrape/pkg/query/predicate"
+	"github.com/milosgajdos/netscrape/pkg/space"
+	"github.com/milosgajdos/netscrape/pkg/space/origin"
+	"github.com/milosgajdos/netscrape/pkg/uuid"
+)
+
+// testStar is a starred repo served by testServer.
+type testStar struct {
+	StarredAt time.Time `json:"starred_at"`
+	Repo      struct {
+		NodeID    string     `json:"node_id"`
+		Name      string     `json:"name"`
+		URL       string     `json:"url"`
+		Language  string     `json:"language,omitempty"`
+		Topics    []string   `json:"topics,omitempty"`
+		Stars     int        `json:"stargazers_count"`
+		Fork      bool       `json:"fork"`
+		CreatedAt *time.Time `json:"created_at,omitempty"`
+		Owner     struct {
+			Login string `json:"login"`
+			Type  string `json:"type"`
+		} `json:"owner"`
+	} `json:"repo"`
+}
+
+func newTestStar(i int, owner string, starredAt time.Time) testStar {
+	s := testStar{StarredAt: starredAt}
+	s.Repo.NodeID = "R" + strconv.Itoa(i)
+	s.Repo.Name = "repo" + strconv.Itoa(i)
+	s.Repo.URL = "https://api.github.com/repos/" + owner + "/" + s.Repo.Name
+	s.Repo.Language = "Go"
+	s.Repo.Topics = []string{"graph"}
+	s.Repo.Owner.Login = owner
+	s.Repo.Owner.Type = "User"
+
+	return s
+}
+
+// testServer is a fake GitHub API server.
+type testServer struct {
+	*httptest.Server
+	// mux routes API requests
+	mux *http.ServeMux
+	// stars are served starred repos of the authenticated user
+	stars []testStar
+	// users are served starred repos of other users
+	users map[string][]testStar
+	// queries are the starred repos request queries
+	queries []url.Values
+	// fail fails the request if it returns true
+	fail func(w http.ResponseWriter, r *http.Request) bool
+	// mu synchronizes access to queries
+	mu sync.Mutex
+}
+
+func newTestServer(stars []testStar) *testServer {
+	ts := &testServer{
+		mux:   http.NewServeMux(),
+		stars: stars,
+		users: make(map[string][]testStar),
+	}
+
+	ts.mux.HandleFunc("/user/starred", ts.starred)
+	ts.mux.HandleFunc("/users/", ts.starred)
+	ts.handle("/user", map[string]string{"login": "me"})
+	ts.Server = httptest.NewServer(ts.mux)
+
+	return ts
+}
+
+// starred serves paginated starred repos.
+func (ts *testServer) starred(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	ts.mu.Lock()
+	ts.queries = append(ts.queries, r.URL.Query())
+	ts.mu.Unlock()
+
+	if ts.fail != nil && ts.fail(w, r) {
+		return
+	}
+
+	served := ts.stars
+	if r.URL.Path != "/user/starred" {
+		user := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/users/"), "/starred")
+		served = ts.users[user]
+	}
+
+	stars := make([]testStar, len(served))
+	copy(stars, served)
+
+	if q.Get("sort") == "created" {
+		sort.Slice(stars, func(i, j int) bool {
+			if q.Get("direction") == "desc" {
+				return stars[i].StarredAt.After(stars[j].StarredAt)
+			}
+			return stars[i].StarredAt.Before(stars[j].StarredAt)
+		})
+	}
+
+	perPage, _ := strconv.Atoi(q.Get("per_page"))
+	if perPage <= 0 {
+		perPage = 30
+	}
+
+	page, _ := strconv.Atoi(q.Get("page"))
+	if page <= 0 {
+		page = 1
+	}
+
+	start := (page - 1) * perPage
+	if start > len(stars) {
+		start = len(stars)
+	}
+
+	end := start + perPage
+	if end > len(stars) {
+		end = len(stars)
+	}
+
+	if end < len(stars) {
+		next := *r.URL
+		q.Set("page", strconv.Itoa(page+1))
+		next.RawQuery = q.Encode()
+		w.Header().Set("Link", fmt.Sprintf(`<%s%s>; rel="next"`, ts.URL, next.String()))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	// nolint:errcheck
+	json.NewEncoder(w).Encode(stars[start:end])
+}
+
+// handle serves v encoded as JSON on the given path.
+func (ts *testServer) handle(path string, v interface{}) {
+	ts.mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		// nolint:errcheck
+		json.NewEncoder(w).Encode(v)
+	})
+}
+
+// client returns GitHub API client of ts.
+func (ts *testServer) client() *github.Client {
+	gh := github.NewClient(nil)
+	gh.BaseURL, _ = url.Parse(ts.URL + "/")
+
+	return gh
+}
+
+// testMap plans and maps GitHub space with s.
+func testMap(ctx context.Context, s *scraper) (space.Top, error) {
+	o, err := origin.New("https://api.github.com")
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := s.Plan(ctx, o)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Map(ctx, p)
+}
+
+// repoNames returns the names of repo entities in top.
+func repoNames(ctx context.Context, top space.Top) ([]string, error) {
+	ents, err := top.Entities(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+
+	for _, e := range ents {
+		if e.Resource().Name() == repoRes {
+			names = append(names, e.Name())
+		}
+	}
+
+	sort.Strings(names)
+
+	return names, nil
+}
+
+func TestMap(t *testing.T) {
+	now := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	var stars []testStar
+	for i := 0; i < 5; i++ {
+		stars = append(stars, newTestStar(i, "owner"+strconv.Itoa(i%2), now.Add(time.Duration(i)*time.Hour)))
+	}
+
+	ts := newTestServer(stars)
+	defer ts.Close()
+
+	s, err := NewScraper(ts.client(), Paging(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	top, err := testMap(context.Background(), s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := repoNames(context.Background(), top)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(names) != len(stars) {
+		t.Errorf("expected repos: %d, got: %d", len(stars), len(names))
+	}
+
+	if len(ts.queries) != 3 {
+		t.Errorf("expected requests: %d, got: %d", 3, len(ts.queries))
+	}
+}
+
+func TestMapIncremental(t *testing.T) {
+	now := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	var stars []testStar
+	for i := 0; i < 5; i++ {
+		stars = append(stars, newTestStar(i, "owner", now.Add(time.Duration(i)*time.Hour)))
+	}
+
+	ts := newTestServer(stars)
+	defer ts.Close()
+
+	checkpoints := NewMemCheckpoints()
+	if err := checkpoints.Put(context.Background(), "", now.Add(2*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewScraper(ts.client(), Paging(1), Incremental(checkpoints))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	top, err := testMap(context.Background(), s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := repoNames(context.Background(), top)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fmt.Sprint(names) != "[repo3 repo4]" {
+		t.Errorf("expected repos: [repo3 repo4], got: %v", names)
+	}
+
+	// NOTE: the third page contains the checkpoint star
+	if len(ts.queries) != 3 {
+		t.Errorf("expected requests: %d, got: %d", 3, len(ts.queries))
+	}
+
+	if q := ts.queries[0]; q.Get("sort") != "created" || q.Get("direction") != "desc" {
+		t.Errorf("expected created desc sort, got: %v", q)
+	}
+
+	checkpoint, err := checkpoints.Get(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := now.Add(4 * time.Hour); !checkpoint.Equal(want) {
+		t.Errorf("expected checkpoint: %v, got: %v", want, checkpoint)
+	}
+
+	top, err = testMap(context.Background(), s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names, err = repoNames(context.Background(), top)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(names) != 0 {
+		t.Errorf("expected no new repos, got: %v", names)
+	}
+}
+
+func TestFileCheckpoints(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoints")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "checkpoints.json")
+
+	c := NewFileCheckpoints(path)
+
+	checkpoint, err := c.Get(context.Background(), "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !checkpoint.IsZero() {
+		t.Errorf("expected zero checkpoint, got: %v", checkpoint)
+	}
+
+	now := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	if err := c.Put(context.Background(), "user", now); err != nil {
+		t.Fatal(err)
+	}
+
+	checkpoint, err = NewFileCheckpoints(path).Get(context.Background(), "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !checkpoint.Equal(now) {
+		t.Errorf("expected checkpoint: %v, got: %v", now, checkpoint)
+	}
+}
+
+func TestMapLanguages(t *testing.T) {
+	ts := newTestServer([]testStar{newTestStar(0, "owner", time.Now())})
+	defer ts.Close()
+
+	ts.handle("/repos/owner/repo0/languages", map[string]int{"Go": 300, "Shell": 100})
+
+	s, err := NewScraper(ts.client(), Languages(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	top, err := testMap(context.Background(), s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	uid, err := uuid.NewFromString("R0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	links, err := top.Links(context.Background(), uid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	weights := make(map[string]string)
+	for _, l := range links {
+		if l.Attrs().Get(attrs.Relation) == langRel {
+			weights[l.To().Value()] = l.Attrs().Get(attrs.Weight)
+		}
+	}
+
+	if len(weights) != 2 {
+		t.Fatalf("expected language links: %d, got: %d", 2, len(weights))
+	}
+
+	for lang, want := range map[string]string{"go-lang": "0.75", "shell-lang": "0.25"} {
+		if w := weights[lang]; w != want {
+			t.Errorf("expected %s weight: %s, got: %s", lang, want, w)
+		}
+	}
+}
+
+func TestMapContributors(t *testing.T) {
+	now := time.Now()
+
+	// NOTE: alice contributes to repo0 and owns repo1
+	ts := newTestServer([]testStar{newTestStar(0, "owner", now), newTestStar(1, "alice", now)})
+	defer ts.Close()
+
+	ts.handle("/repos/owner/repo0/contributors", []map[string]interface{}{
+		{"login": "owner", "type": "User", "contributions": 10},
+		{"login": "alice", "type": "User", "contributions": 5},
+		{"login": "bob", "type": "User", "contributions": 1},
+	})
+	ts.handle("/repos/alice/repo1/contributors", []map[string]interface{}{})
+
+	s, err := NewScraper(ts.client(), Contributors(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	top, err := testMap(context.Background(), s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ents, err := top.Entities(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var repo0 space.Entity
+
+	owners := make(map[string]string)
+	contributors := make(map[string]bool)
+	for _, e := range ents {
+		switch e.Resource().Name() {
+		case ownerRes:
+			owners[e.Name()] = e.Attrs().Get(ownerTypeAttr)
+		case contributorRes:
+			contributors[e.Name()] = true
+		case repoRes:
+			if e.Name() == "repo0" {
+				repo0 = e
+			}
+		}
+	}
+
+	if len(owners) != 2 || owners["owner"] != "User" || owners["alice"] != "User" {
+		t.Fatalf("unexpected owners: %v", owners)
+	}
+
+	// NOTE: repo owner contributions are linked from the owner entity
+	if len(contributors) != 1 || !contributors["alice"] {
+		t.Fatalf("unexpected contributors: %v", contributors)
+	}
+
+	if repo0 == nil {
+		t.Fatal("repo0 not mapped")
+	}
+
+	ownerUID, err := s.userUID("owner")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	aliceUID, err := s.contributorUID("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for login, want := range map[string]struct {
+		uid         uuid.UID
+		rel, weight string
+	}{
+		"owner": {ownerUID, ownerRel, "10"},
+		"alice": {aliceUID, contributorRel, "5"},
+	} {
+		uid := want.uid
+
+		links, err := top.Links(context.Background(), uid)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var repoLinks []space.Link
+		for _, l := range links {
+			if l.To().Value() == repo0.UID().Value() {
+				repoLinks = append(repoLinks, l)
+			}
+		}
+
+		if len(repoLinks) != 1 {
+			t.Fatalf("expected %s links: %d, got: %d", login, 1, len(repoLinks))
+		}
+
+		a := repoLinks[0].Attrs()
+		if a.Get(attrs.Relation) != want.rel || a.Get(attrs.Weight) != want.weight {
+			t.Errorf("expected %s link %s/%s, got: %s/%s", login, want.rel, want.weight, a.Get(attrs.Relation), a.Get(attrs.Weight))
+		}
+	}
+}
+
+func TestMapForks(t *testing.T) {
+	now := time.Now()
+
+	fork := newTestStar(0, "owner", now)
+	fork.Repo.Fork = true
+
+	// NOTE: parent is starred, too
+	stars := []testStar{fork, newTestStar(9, "alice", now.Add(-time.Hour))}
+
+	ts := newTestServer(stars)
+	defer ts.Close()
+
+	upstream := func(i int, owner string) map[string]interface{} {
+		s := newTestStar(i, owner, now)
+		return map[string]interface{}{
+			"node_id": s.Repo.NodeID,
+			"name":    s.Repo.Name,
+			"url":     s.Repo.URL,
+			"fork":    owner == "alice",
+			"owner":   map[string]string{"login": owner},
+		}
+	}
+
+	ts.handle("/repos/owner/repo0", map[string]interface{}{
+		"node_id": fork.Repo.NodeID,
+		"name":    fork.Repo.Name,
+		"fork":    true,
+		"owner":   map[string]string{"login": "owner"},
+		"parent":  upstream(9, "alice"),
+		"source":  upstream(8, "bob"),
+	})
+
+	s, err := NewScraper(ts.client(), Forks(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	top, err := testMap(context.Background(), s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := repoNames(context.Background(), top)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fmt.Sprint(names) != "[repo0 repo8 repo9]" {
+		t.Errorf("expected repos: [repo0 repo8 repo9], got: %v", names)
+	}
+
+	uid, err := uuid.NewFromString(fork.Repo.NodeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	links, err := top.Links(context.Background(), uid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	upstreams := make(map[string]string)
+	for _, l := range links {
+		if l.Attrs().Get(attrs.Relation) == forkRel {
+			upstreams[l.To().Value()] = l.Attrs().Get("upstream")
+		}
+	}
+
+	if len(upstreams) != 2 || upstreams["R9"] != upstreamParent || upstreams["R8"] != upstreamSource {
+		t.Errorf("unexpected upstream links: %v", upstreams)
+	}
+
+	for _, id := range []string{"R9", "R8"} {
+		uid, err := uuid.NewFromString(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ents, err := top.Get(context.Background(), base.Build().Add(predicate.UID(uid)))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(ents) != 1 {
+			t.Fatalf("expected %s entities: %d, got: %d", id, 1, len(ents))
+		}
+
+		starred := ents[0].Attrs().Get("starred_at") != ""
+		if want := id == "R9"; starred != want {
+			t.Errorf("expected %s starred: %v, got: %v", id, want, starred)
+		}
+	}
+}
+
+func TestMapUsers(t *testing.T) {
+	now := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	ts := newTestServer(nil)
+	defer ts.Close()
+
+	// NOTE: repo1 is starred by both users
+	ts.users["alice"] = []testStar{newTestStar(0, "owner", now), newTestStar(1, "owner", now)}
+	ts.users["bob"] = []testStar{newTestStar(1, "owner", now.Add(time.Hour)), newTestStar(2, "bob", now)}
+
+	checkpoints := NewMemCheckpoints()
+
+	s, err := NewScraper(ts.client(), Users("alice", "bob", "alice"), Paging(1), Incremental(checkpoints))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	top, err := testMap(context.Background(), s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := repoNames(context.Background(), top)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fmt.Sprint(names) != "[repo0 repo1 repo2]" {
+		t.Errorf("expected repos: [repo0 repo1 repo2], got: %v", names)
+	}
+
+	ents, err := top.Entities(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// NOTE: repos starred by several users are shared so only the links are starred
+	for _, e := range ents {
+		if e.Resource().Name() == repoRes && e.Attrs().Get("starred_at") != "" {
+			t.Errorf("unexpected repo %s starred_at: %s", e.Name(), e.Attrs().Get("starred_at"))
+		}
+	}
+
+	for user, want := range map[string]map[string]string{
+		"alice": {"R0": now.Format(dateTime), "R1": now.Format(dateTime)},
+		"bob":   {"R1": now.Add(time.Hour).Format(dateTime), "R2": now.Format(dateTime)},
+	} {
+		uid, err := s.stargazerUID(user)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		links, err := top.Links(context.Background(), uid)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		starred := make(map[string]string)
+		for _, l := range links {
+			if l.Attrs().Get(attrs.Relation) == stargazerRel {
+				starred[l.To().Value()] = l.Attrs().Get("starred_at")
+			}
+		}
+
+		if fmt.Sprint(starred) != fmt.Sprint(want) {
+			t.Errorf("expected %s stars: %v, got: %v", user, want, starred)
+		}
+
+		checkpoint, err := checkpoints.Get(context.Background(), user)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if checkpoint.IsZero() {
+			t.Errorf("expected %s checkpoint", user)
+		}
+	}
+
+	// NOTE: bob stars a repo owned by bob, but the stargazer doesn't share the owner uid
+	uid, err := s.userUID("bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	links, err := top.Links(context.Background(), uid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(links) != 1 || links[0].Attrs().Get(attrs.Relation) != ownerRel {
+		t.Errorf("expected bob to own a single repo")
+	}
+}
+
+func TestMapStargazer(t *testing.T) {
+	ts := newTestServer([]testStar{newTestStar(0, "owner", time.Now())})
+	defer ts.Close()
+
+	s, err := NewScraper(ts.client())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	top, err := testMap(context.Background(), s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	uid, err := s.stargazerUID("me")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ents, err := top.Get(context.Background(), base.Build().Add(predicate.UID(uid)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ents) != 1 || ents[0].Resource().Name() != stargazerRes {
+		t.Fatalf("expected authenticated user stargazer")
+	}
+
+	links, err := top.Links(context.Background(), uid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(links) != 1 || links[0].To().Value() != "R0" {
+		t.Errorf("expected stargazer link to R0")
+	}
+}
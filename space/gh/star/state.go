@@ -0,0 +1,241 @@
+package star
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/google/go-github/v32/github"
+)
+
+// Page is a page of starred repos.
+type Page struct {
+	// Number is the page number
+	Number int `json:"number"`
+	// Size is the page size
+	Size int `json:"size"`
+	// Stars are the starred repos
+	Stars []*github.StarredRepository `json:"stars"`
+	// Cursor is GraphQL API cursor of the page
+	Cursor string `json:"cursor,omitempty"`
+	// Languages are prefetched repo language sizes keyed by repo node ID
+	Languages map[string]map[string]int `json:"languages,omitempty"`
+}
+
+// StateStore stores the state of interrupted scrapes.
+// The state consists of the pages which have been completely mapped.
+type StateStore interface {
+	// Load returns the completed pages of the given user in the order they were appended.
+	// It returns no pages if there is no state.
+	Load(ctx context.Context, user string) ([]*Page, error)
+	// Append appends a completed page to the state of the given user.
+	Append(ctx context.Context, user string, p *Page) error
+	// Clear removes the state of the given user.
+	Clear(ctx context.Context, user string) error
+}
+
+// MemState is in-memory state store.
+type MemState struct {
+	pages map[string][]*Page
+	mu    *sync.Mutex
+}
+
+// NewMemState creates a new in-memory state store and returns it.
+func NewMemState() *MemState {
+	return &MemState{
+		pages: make(map[string][]*Page),
+		mu:    &sync.Mutex{},
+	}
+}
+
+// Load returns the completed pages of the given user.
+func (m *MemState) Load(ctx context.Context, user string) ([]*Page, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	pages := make([]*Page, len(m.pages[user]))
+	copy(pages, m.pages[user])
+
+	return pages, nil
+}
+
+// Append appends a completed page to the state of the given user.
+func (m *MemState) Append(ctx context.Context, user string, p *Page) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.pages[user] = append(m.pages[user], p)
+
+	return nil
+}
+
+// Clear removes the state of the given user.
+func (m *MemState) Clear(ctx context.Context, user string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.pages, user)
+
+	return nil
+}
+
+// FileState is state store persisted in local files.
+// The state of every user is stored in a separate file
+// which contains a JSON encoded page per line.
+type FileState struct {
+	dir string
+	mu  *sync.Mutex
+}
+
+// NewFileState creates a new state store persisted in the given directory.
+// The directory is created when the first page is appended.
+func NewFileState(dir string) *FileState {
+	return &FileState{
+		dir: dir,
+		mu:  &sync.Mutex{},
+	}
+}
+
+// path returns the path of the state file of the given user.
+func (f *FileState) path(user string) string {
+	return filepath.Join(f.dir, "star-"+url.PathEscape(user)+".jsonl")
+}
+
+// Load returns the completed pages of the given user.
+// NOTE: a truncated last page, e.g. left by a crash, is ignored.
+func (f *FileState) Load(ctx context.Context, user string) ([]*Page, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	file, err := os.Open(f.path(user))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	var pages []*Page
+
+	dec := json.NewDecoder(file)
+
+	for {
+		p := new(Page)
+
+		if err := dec.Decode(p); err != nil {
+			if err == io.EOF || errors.Is(err, io.ErrUnexpectedEOF) {
+				return pages, nil
+			}
+			return nil, fmt.Errorf("load state: %w", err)
+		}
+
+		pages = append(pages, p)
+	}
+}
+
+// Append appends a completed page to the state of the given user.
+func (f *FileState) Append(ctx context.Context, user string, p *Page) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := os.MkdirAll(f.dir, 0755); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(f.path(user), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	if err := json.NewEncoder(file).Encode(p); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// Clear removes the state of the given user.
+func (f *FileState) Clear(ctx context.Context, user string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := os.Remove(f.path(user)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+// progress records the pages completed by mapping workers.
+// Pages are appended to state store in order so the stored
+// state never contains gaps even if pages complete out of order.
+type progress struct {
+	// state stores completed pages
+	state StateStore
+	// user is the scraped user
+	user string
+	// next is the number of the next page to be stored
+	next int
+	// done are completed pages which can't be stored yet
+	done map[int]*Page
+	// mu synchronizes access to progress
+	mu *sync.Mutex
+}
+
+// newProgress creates a new progress which stores completed pages starting with page next.
+func newProgress(state StateStore, user string, next int) *progress {
+	return &progress{
+		state: state,
+		user:  user,
+		next:  next,
+		done:  make(map[int]*Page),
+		mu:    &sync.Mutex{},
+	}
+}
+
+// complete records p as completed and stores all the completed pages which follow the stored ones.
+// NOTE: it is safe to call complete on nil progress.
+func (p *progress) complete(ctx context.Context, page *Page) error {
+	if p == nil {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.done[page.Number] = page
+
+	for {
+		next, ok := p.done[p.next]
+		if !ok {
+			return nil
+		}
+
+		if err := p.state.Append(ctx, p.user, next); err != nil {
+			return err
+		}
+
+		delete(p.done, p.next)
+		p.next++
+	}
+}
+
+// checkPages checks if the stored pages can be resumed with the given page size.
+// It returns ErrInvalidState if the pages are not contiguous or their size differs.
+func checkPages(pages []*Page, size int) error {
+	for i, p := range pages {
+		if p.Number != i+1 || p.Size != size {
+			return fmt.Errorf("page %d of size %d: %w", p.Number, p.Size, ErrInvalidState)
+		}
+	}
+
+	return nil
+}
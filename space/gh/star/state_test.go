@@ -0,0 +1,188 @@
+package star
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v32/github"
+)
+
+func TestMapResume(t *testing.T) {
+	now := time.Now()
+
+	var stars []testStar
+	for i := 0; i < 5; i++ {
+		stars = append(stars, newTestStar(i, "owner", now))
+	}
+
+	ts := newTestServer(stars)
+	defer ts.Close()
+
+	ts.fail = func(w http.ResponseWriter, r *http.Request) bool {
+		if r.URL.Query().Get("page") != "3" {
+			return false
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		return true
+	}
+
+	state := NewMemState()
+
+	s, err := NewScraper(ts.client(), Paging(1), Workers(2), State(state))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := testMap(context.Background(), s); err == nil {
+		t.Fatal("expected error")
+	}
+
+	pages, err := state.Load(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(pages) != 2 {
+		t.Fatalf("expected stored pages: %d, got: %d", 2, len(pages))
+	}
+
+	ts.fail = nil
+	ts.queries = nil
+
+	s, err = NewScraper(ts.client(), Paging(1), Workers(2), State(state), Resume(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	top, err := testMap(context.Background(), s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := repoNames(context.Background(), top)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(names) != len(stars) {
+		t.Errorf("expected repos: %d, got: %d", len(stars), len(names))
+	}
+
+	if len(ts.queries) != 3 || ts.queries[0].Get("page") != "3" {
+		t.Errorf("expected 3 requests starting with page 3, got: %v", ts.queries)
+	}
+
+	if pages, _ := state.Load(context.Background(), ""); len(pages) != 0 {
+		t.Errorf("expected state to be cleared, got: %d pages", len(pages))
+	}
+}
+
+func TestMapResumeInvalidState(t *testing.T) {
+	ts := newTestServer(nil)
+	defer ts.Close()
+
+	state := NewMemState()
+	if err := state.Append(context.Background(), "", &Page{Number: 1, Size: 10}); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewScraper(ts.client(), Paging(1), State(state), Resume(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := testMap(context.Background(), s); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("got: %v, want: %v", err, ErrInvalidState)
+	}
+}
+
+func TestProgress(t *testing.T) {
+	state := NewMemState()
+	prog := newProgress(state, "user", 1)
+
+	for _, n := range []int{2, 3, 1, 5} {
+		if err := prog.complete(context.Background(), &Page{Number: n}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pages, err := state.Load(context.Background(), "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(pages) != 3 {
+		t.Fatalf("expected stored pages: %d, got: %d", 3, len(pages))
+	}
+
+	for i, p := range pages {
+		if p.Number != i+1 {
+			t.Errorf("expected page: %d, got: %d", i+1, p.Number)
+		}
+	}
+}
+
+func TestFileState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	state := NewFileState(dir)
+
+	pages, err := state.Load(context.Background(), "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(pages) != 0 {
+		t.Fatalf("expected no pages, got: %d", len(pages))
+	}
+
+	for i := 1; i <= 2; i++ {
+		repo := &github.StarredRepository{Repository: &github.Repository{Name: github.String("repo" + strconv.Itoa(i))}}
+		if err := state.Append(context.Background(), "user", &Page{Number: i, Size: 1, Stars: []*github.StarredRepository{repo}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// simulate truncated write
+	f, err := os.OpenFile(filepath.Join(dir, "star-user.jsonl"), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.WriteString(`{"number": 3, "size"`); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	pages, err = state.Load(context.Background(), "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(pages) != 2 {
+		t.Fatalf("expected pages: %d, got: %d", 2, len(pages))
+	}
+
+	if name := pages[1].Stars[0].Repository.GetName(); name != "repo2" {
+		t.Errorf("expected repo: %s, got: %s", "repo2", name)
+	}
+
+	if err := state.Clear(context.Background(), "user"); err != nil {
+		t.Fatal(err)
+	}
+
+	if pages, _ := state.Load(context.Background(), "user"); len(pages) != 0 {
+		t.Errorf("expected no pages after clear, got: %d", len(pages))
+	}
+}
@@ -0,0 +1,397 @@
+package star
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/milosgajdos/netscrape/pkg/attrs"
+	"github.com/milosgajdos/netscrape/pkg/query"
+	"github.com/milosgajdos/netscrape/pkg/space"
+	"github.com/milosgajdos/netscrape/pkg/space/entity"
+	"github.com/milosgajdos/netscrape/pkg/store"
+	"github.com/milosgajdos/netscrape/pkg/uuid"
+)
+
+const (
+	// DefaultStreamBuffer is default number of buffered store writes.
+	DefaultStreamBuffer = 1000
+	// DefaultStreamCache is default number of cached entities and links.
+	DefaultStreamCache = 100000
+)
+
+// StreamOptions configure streaming.
+type StreamOptions struct {
+	// Buffer is the number of buffered store writes
+	Buffer int
+	// CacheSize is the number of entities and links whose
+	// attributes are cached to skip redundant store writes
+	CacheSize int
+}
+
+// StreamOption is streaming option.
+type StreamOption func(*StreamOptions)
+
+// StreamBuffer configures the number of buffered store writes.
+// Mapping blocks when the buffer is full until the store catches up.
+func StreamBuffer(n int) StreamOption {
+	return func(o *StreamOptions) {
+		o.Buffer = n
+	}
+}
+
+// StreamCache configures the number of cached entities and links.
+// NOTE: the attributes of the stored entities are cached separately,
+// so up to twice as many entity attributes are kept in memory.
+func StreamCache(n int) StreamOption {
+	return func(o *StreamOptions) {
+		o.CacheSize = n
+	}
+}
+
+// Stream maps GH stars space topology straight into st as the starred pages are mapped.
+// Unlike Map it does not keep the topology in memory: store writes are buffered
+// and mapping blocks until st catches up with the buffered writes.
+// Entities are merged into the stored entities like Map merges them into the mapped ones,
+// so the attributes of the stored entities are kept unless they are mapped again.
+// The attributes of the most recently written entities and links are cached,
+// so unchanged entities and links are not rewritten.
+// NOTE: st must upsert entities and links which have already been stored.
+// Link attributes can't be read from st, so they are only merged with cached attributes;
+// links evicted from the cache are rewritten with the mapped attributes.
+// It returns the first error returned by either the mapping or st.
+// If scraping is partial, the mapping errors are returned as MapError.
+func (s *scraper) Stream(ctx context.Context, p space.Plan, st store.Store, opts ...StreamOption) error {
+	sopts := StreamOptions{
+		Buffer:    DefaultStreamBuffer,
+		CacheSize: DefaultStreamCache,
+	}
+
+	for _, apply := range opts {
+		apply(&sopts)
+	}
+
+	top := newStoreTop(st, sopts)
+
+	done := make(chan struct{})
+	go func() {
+		top.write(ctx)
+		close(done)
+	}()
+
+	err := s.mapTop(ctx, p, top)
+
+	close(top.ops)
+	<-done
+
+	if err != nil {
+		return err
+	}
+
+	return top.err()
+}
+
+// storeOp is a buffered store write.
+type storeOp func(context.Context) error
+
+// storeTop is space topology which writes entities and links to store.
+// It implements just enough of space.Top to be mapped into.
+type storeTop struct {
+	st    store.Store
+	ops   chan storeOp
+	cache *attrsCache
+	// stored caches the attributes of the stored entities;
+	// it is only accessed by the store writer so it needs no locking
+	stored *attrsCache
+	// mu serializes cache updates with buffering of store writes
+	mu *sync.Mutex
+	// emu guards the first store write error
+	emu   *sync.Mutex
+	wrErr error
+}
+
+// newStoreTop creates a new storeTop and returns it.
+func newStoreTop(st store.Store, opts StreamOptions) *storeTop {
+	return &storeTop{
+		st:     st,
+		ops:    make(chan storeOp, opts.Buffer),
+		cache:  newAttrsCache(opts.CacheSize),
+		stored: newAttrsCache(opts.CacheSize),
+		mu:     &sync.Mutex{},
+		emu:    &sync.Mutex{},
+	}
+}
+
+// write applies buffered store writes until the buffer is closed.
+// Store writes are skipped once any of them fails, but the buffer is still drained.
+func (t *storeTop) write(ctx context.Context) {
+	for op := range t.ops {
+		if t.err() != nil {
+			continue
+		}
+
+		if err := op(ctx); err != nil {
+			t.emu.Lock()
+			t.wrErr = err
+			t.emu.Unlock()
+		}
+	}
+}
+
+// err returns the first store write error.
+func (t *storeTop) err() error {
+	t.emu.Lock()
+	defer t.emu.Unlock()
+
+	return t.wrErr
+}
+
+// enqueue buffers store write op.
+// It blocks until there is a space in the buffer or ctx is done.
+func (t *storeTop) enqueue(ctx context.Context, op storeOp) error {
+	if err := t.err(); err != nil {
+		return err
+	}
+
+	select {
+	case t.ops <- op:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Add writes a copy of e to store.
+// The attributes of the stored entity missing in e are merged into the copy
+// when it's written, so the merge does not depend on the cached attributes.
+// The stored entity is only read from store if its attributes are not cached.
+// It does not rewrite e if its attributes have not changed since it was cached.
+func (t *storeTop) Add(ctx context.Context, e space.Entity, opts ...space.Option) error {
+	a := attrs.NewCopyFrom(e.Attrs())
+
+	ent, err := entity.New(e.Name(), e.Namespace(), e.Resource(), entity.WithUID(e.UID()), entity.WithAttrs(a))
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.cache.merge(e.UID().Value(), a, true) {
+		return nil
+	}
+
+	return t.enqueue(ctx, func(ctx context.Context) error {
+		if err := t.merge(ctx, ent); err != nil {
+			return err
+		}
+
+		if err := t.st.Add(ctx, ent); err != nil {
+			return err
+		}
+
+		t.stored.merge(ent.UID().Value(), ent.Attrs(), false)
+
+		return nil
+	})
+}
+
+// merge copies the attributes of the stored entity missing in e to e.
+// The stored attributes are read from store unless they are cached.
+// NOTE: it is called by the store writer, so it sees all the preceding writes.
+func (t *storeTop) merge(ctx context.Context, e space.Entity) error {
+	if m, ok := t.stored.get(e.UID().Value()); ok {
+		for k, v := range m {
+			if e.Attrs().Get(k) == "" {
+				e.Attrs().Set(k, v)
+			}
+		}
+		return nil
+	}
+
+	stored, err := t.st.Get(ctx, e.UID())
+	if err != nil {
+		if errors.Is(err, store.ErrEntityNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	a := stored.Attrs()
+	if a == nil {
+		return nil
+	}
+
+	for _, k := range a.Keys() {
+		if e.Attrs().Get(k) == "" {
+			e.Attrs().Set(k, a.Get(k))
+		}
+	}
+
+	return nil
+}
+
+// Remove deletes the entity with the given uid from store.
+func (t *storeTop) Remove(ctx context.Context, uid uuid.UID, opts ...space.Option) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.cache.remove(uid.Value())
+
+	return t.enqueue(ctx, func(ctx context.Context) error {
+		t.stored.remove(uid.Value())
+		return t.st.Delete(ctx, uid)
+	})
+}
+
+// Entities is not implemented; streamed entities are only available in store.
+func (t *storeTop) Entities(ctx context.Context) ([]space.Entity, error) {
+	return nil, store.ErrNotImplemented
+}
+
+// Get returns no entities; streamed entities are only available in store.
+// NOTE: entities which are not found are added, and Add merges them into the stored ones.
+func (t *storeTop) Get(ctx context.Context, q query.Query) ([]space.Entity, error) {
+	return []space.Entity{}, nil
+}
+
+// Link writes the link between entities with the given UIDs to store.
+// If merging is requested, the cached link attributes are merged into the link attributes.
+// It does not rewrite the link if its attributes have not changed.
+func (t *storeTop) Link(ctx context.Context, from, to uuid.UID, opts ...space.Option) error {
+	lopts := space.Options{}
+	for _, apply := range opts {
+		apply(&lopts)
+	}
+
+	a := lopts.Attrs
+	if a == nil {
+		var err error
+		if a, err = attrs.New(); err != nil {
+			return err
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := from.Value() + "->" + to.Value()
+
+	if !t.cache.merge(key, a, lopts.Merge) {
+		return nil
+	}
+
+	return t.enqueue(ctx, func(ctx context.Context) error {
+		return t.st.Link(ctx, from, to, store.WithAttrs(a))
+	})
+}
+
+// Links is not implemented; streamed links are only available in store.
+func (t *storeTop) Links(ctx context.Context, uid uuid.UID) ([]space.Link, error) {
+	return nil, store.ErrNotImplemented
+}
+
+// cacheItem is attrsCache item.
+type cacheItem struct {
+	key   string
+	attrs map[string]string
+}
+
+// attrsCache caches the attributes of the most recently written entities and links.
+// The least recently used attributes are evicted when the cache is full.
+// NOTE: attrsCache is not safe for concurrent use.
+type attrsCache struct {
+	size  int
+	items map[string]*list.Element
+	lru   *list.List
+}
+
+// newAttrsCache creates a new attrsCache which caches at most size items and returns it.
+func newAttrsCache(size int) *attrsCache {
+	return &attrsCache{
+		size:  size,
+		items: make(map[string]*list.Element),
+		lru:   list.New(),
+	}
+}
+
+// merge caches attributes a under key.
+// If merge is true, the cached attributes missing in a are copied to a first.
+// It returns true if a differs from the cached attributes or if key has not been cached.
+func (c *attrsCache) merge(key string, a attrs.Attrs, merge bool) bool {
+	el, ok := c.items[key]
+	if !ok {
+		if c.size <= 0 {
+			return true
+		}
+
+		c.items[key] = c.lru.PushFront(&cacheItem{key: key, attrs: attrsMap(a)})
+
+		if c.lru.Len() > c.size {
+			oldest := c.lru.Back()
+			c.lru.Remove(oldest)
+			delete(c.items, oldest.Value.(*cacheItem).key)
+		}
+
+		return true
+	}
+
+	c.lru.MoveToFront(el)
+	item := el.Value.(*cacheItem)
+
+	if merge {
+		for k, v := range item.attrs {
+			if a.Get(k) == "" {
+				a.Set(k, v)
+			}
+		}
+	}
+
+	m := attrsMap(a)
+
+	changed := len(m) != len(item.attrs)
+	for k, v := range m {
+		if item.attrs[k] != v {
+			changed = true
+			break
+		}
+	}
+
+	item.attrs = m
+
+	return changed
+}
+
+// get returns the attributes cached under key.
+func (c *attrsCache) get(key string) (map[string]string, bool) {
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+
+	c.lru.MoveToFront(el)
+
+	return el.Value.(*cacheItem).attrs, true
+}
+
+// remove evicts key from cache.
+func (c *attrsCache) remove(key string) {
+	if el, ok := c.items[key]; ok {
+		c.lru.Remove(el)
+		delete(c.items, key)
+	}
+}
+
+// attrsMap returns attributes a as map.
+func attrsMap(a attrs.Attrs) map[string]string {
+	m := make(map[string]string)
+	for _, k := range a.Keys() {
+		m[k] = a.Get(k)
+	}
+
+	return m
+}
+
+// compile time check
+var _ space.Top = (*storeTop)(nil)
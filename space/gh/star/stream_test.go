@@ -0,0 +1,340 @@
+package star
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/milosgajdos/netscrape/pkg/attrs"
+	"github.com/milosgajdos/netscrape/pkg/space"
+	"github.com/milosgajdos/netscrape/pkg/space/origin"
+	"github.com/milosgajdos/netscrape/pkg/store"
+	"github.com/milosgajdos/netscrape/pkg/uuid"
+)
+
+// testStream streams GH stars space topology into st.
+func testStream(ctx context.Context, s *scraper, st store.Store, opts ...StreamOption) error {
+	o, err := origin.New("https://api.github.com")
+	if err != nil {
+		return err
+	}
+
+	p, err := s.Plan(ctx, o)
+	if err != nil {
+		return err
+	}
+
+	return s.Stream(ctx, p, st, opts...)
+}
+
+// testStore is store which replaces stored entities and links.
+// NOTE: entity attributes are not merged by the store itself.
+type testStore struct {
+	mu    *sync.Mutex
+	ents  map[string]store.Entity
+	links map[string]map[string]string
+}
+
+func newTestStore() *testStore {
+	return &testStore{
+		mu:    &sync.Mutex{},
+		ents:  make(map[string]store.Entity),
+		links: make(map[string]map[string]string),
+	}
+}
+
+func (s *testStore) Add(ctx context.Context, e store.Entity, opts ...store.Option) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.ents[e.UID().Value()] = e
+	return nil
+}
+
+func (s *testStore) Get(ctx context.Context, uid uuid.UID, opts ...store.Option) (store.Entity, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.ents[uid.Value()]
+	if !ok {
+		return nil, store.ErrEntityNotFound
+	}
+	return e, nil
+}
+
+func (s *testStore) Delete(ctx context.Context, uid uuid.UID, opts ...store.Option) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.ents, uid.Value())
+	return nil
+}
+
+func (s *testStore) Link(ctx context.Context, from, to uuid.UID, opts ...store.Option) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.links[from.Value()+"->"+to.Value()] = attrsMap(storeOptions(opts...).Attrs)
+	return nil
+}
+
+func (s *testStore) Unlink(ctx context.Context, from, to uuid.UID, opts ...store.Option) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.links, from.Value()+"->"+to.Value())
+	return nil
+}
+
+// storeOptions returns store options configured with opts.
+func storeOptions(opts ...store.Option) store.Options {
+	o := store.Options{}
+	for _, apply := range opts {
+		apply(&o)
+	}
+
+	return o
+}
+
+// countStore is store which counts entity reads.
+type countStore struct {
+	*testStore
+	gets map[string]int
+}
+
+func (c *countStore) Get(ctx context.Context, uid uuid.UID, opts ...store.Option) (store.Entity, error) {
+	c.mu.Lock()
+	c.gets[uid.Value()]++
+	c.mu.Unlock()
+
+	return c.testStore.Get(ctx, uid, opts...)
+}
+
+// failStore is store which fails to link entities.
+type failStore struct {
+	store.Store
+	err error
+}
+
+func (f *failStore) Link(ctx context.Context, from, to uuid.UID, opts ...store.Option) error {
+	return f.err
+}
+
+func TestStream(t *testing.T) {
+	now := time.Now()
+
+	fork := newTestStar(0, "owner", now)
+	fork.Repo.Fork = true
+	fork.Repo.Topics = []string{"graph", "cli"}
+
+	// NOTE: parent is starred and owned by a contributor of the fork
+	ts := newTestServer([]testStar{fork, newTestStar(9, "alice", now.Add(-time.Hour))})
+	defer ts.Close()
+
+	ts.handle("/repos/owner/repo0", map[string]interface{}{
+		"node_id": fork.Repo.NodeID,
+		"name":    fork.Repo.Name,
+		"fork":    true,
+		"owner":   map[string]string{"login": "owner"},
+		"parent": map[string]interface{}{
+			"node_id":           "R9",
+			"name":              "repo9",
+			"owner":             map[string]string{"login": "alice", "type": "User"},
+			"open_issues_count": 3,
+		},
+	})
+	ts.handle("/repos/owner/repo0/contributors", []map[string]interface{}{
+		{"login": "owner", "type": "User", "contributions": 10},
+		{"login": "alice", "type": "User", "contributions": 5},
+	})
+	ts.handle("/repos/alice/repo9/contributors", []map[string]interface{}{
+		{"login": "alice", "type": "User", "contributions": 1},
+	})
+
+	// NOTE: a single worker maps the repos in the same order
+	s, err := NewScraper(ts.client(), Paging(1), Workers(1), Forks(true), Contributors(2), RepoAttrs(AllRepoAttrs()...))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	top, err := testMap(context.Background(), s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	st := newTestStore()
+
+	// NOTE: tiny buffer and cache exercise backpressure and cache evictions
+	if err := testStream(context.Background(), s, st, StreamBuffer(1), StreamCache(1)); err != nil {
+		t.Fatal(err)
+	}
+
+	ents, err := top.Entities(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(st.ents) != len(ents) {
+		t.Errorf("expected stored entities: %d, got: %d", len(ents), len(st.ents))
+	}
+
+	var links int
+
+	// NOTE: every entity and link of the mapped topology must be stored
+	for _, e := range ents {
+		stored, err := st.Get(context.Background(), e.UID())
+		if err != nil {
+			t.Errorf("failed to get %s/%s: %v", e.Resource().Name(), e.Name(), err)
+			continue
+		}
+
+		if got, want := fmt.Sprint(attrsMap(stored.Attrs())), fmt.Sprint(attrsMap(e.Attrs())); got != want {
+			t.Errorf("entity %s/%s: expected attrs: %s, got: %s", e.Resource().Name(), e.Name(), want, got)
+		}
+
+		if r := stored.(space.Entity).Resource().Name(); r != e.Resource().Name() {
+			t.Errorf("entity %s: expected resource: %s, got: %s", e.Name(), e.Resource().Name(), r)
+		}
+
+		mapped, err := top.Links(context.Background(), e.UID())
+		if err != nil {
+			if errors.Is(err, space.ErrEntityNotFound) {
+				continue
+			}
+			t.Fatal(err)
+		}
+
+		for _, l := range mapped {
+			links++
+
+			a, ok := st.links[l.From().Value()+"->"+l.To().Value()]
+			if !ok {
+				t.Errorf("failed to get link %s->%s", l.From().Value(), l.To().Value())
+				continue
+			}
+
+			if got, want := fmt.Sprint(a), fmt.Sprint(attrsMap(l.Attrs())); got != want {
+				t.Errorf("link %s->%s: expected attrs: %s, got: %s", l.From().Value(), l.To().Value(), want, got)
+			}
+		}
+	}
+
+	if len(st.links) != links {
+		t.Errorf("expected stored links: %d, got: %d", links, len(st.links))
+	}
+}
+
+func TestStreamGets(t *testing.T) {
+	now := time.Now()
+
+	fork := newTestStar(0, "owner", now)
+	fork.Repo.Fork = true
+
+	// NOTE: parent is written as the upstream of the fork and again when it's starred
+	ts := newTestServer([]testStar{fork, newTestStar(9, "alice", now.Add(-time.Hour))})
+	defer ts.Close()
+
+	ts.handle("/repos/owner/repo0", map[string]interface{}{
+		"node_id": fork.Repo.NodeID,
+		"name":    fork.Repo.Name,
+		"fork":    true,
+		"owner":   map[string]string{"login": "owner"},
+		"parent": map[string]interface{}{
+			"node_id": "R9",
+			"name":    "repo9",
+			"owner":   map[string]string{"login": "alice", "type": "User"},
+		},
+	})
+
+	s, err := NewScraper(ts.client(), Paging(1), Workers(1), Forks(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	st := &countStore{testStore: newTestStore(), gets: make(map[string]int)}
+
+	if err := testStream(context.Background(), s, st); err != nil {
+		t.Fatal(err)
+	}
+
+	// NOTE: the stored attributes missing in the mapped entities must be kept
+	st.ents["R9"].Attrs().Set("note", "kept")
+	st.gets = make(map[string]int)
+
+	if err := testStream(context.Background(), s, st); err != nil {
+		t.Fatal(err)
+	}
+
+	for uid, n := range st.gets {
+		if n > 1 {
+			t.Errorf("entity %s: expected store reads: %d, got: %d", uid, 1, n)
+		}
+	}
+
+	if st.gets["R9"] != 1 {
+		t.Errorf("expected R9 store reads: %d, got: %d", 1, st.gets["R9"])
+	}
+
+	if v := st.ents["R9"].Attrs().Get("note"); v != "kept" {
+		t.Errorf("expected stored attribute: %q, got: %q", "kept", v)
+	}
+}
+
+func TestStreamError(t *testing.T) {
+	ts := newTestServer([]testStar{newTestStar(0, "owner", time.Now())})
+	defer ts.Close()
+
+	s, err := NewScraper(ts.client())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errLink := errors.New("link failed")
+
+	if err := testStream(context.Background(), s, &failStore{Store: newTestStore(), err: errLink}); !errors.Is(err, errLink) {
+		t.Errorf("expected error: %v, got: %v", errLink, err)
+	}
+}
+
+func TestAttrsCache(t *testing.T) {
+	c := newAttrsCache(1)
+
+	a, err := attrs.NewFromMap(map[string]string{"k": "v"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !c.merge("a", a, true) {
+		t.Errorf("expected uncached attrs to change")
+	}
+
+	b := attrs.NewCopyFrom(a)
+	if c.merge("a", b, true) {
+		t.Errorf("expected unchanged attrs")
+	}
+
+	b.Set("x", "y")
+	if !c.merge("a", b, true) {
+		t.Errorf("expected changed attrs")
+	}
+
+	m, err := attrs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.merge("a", m, true) || m.Get("k") != "v" || m.Get("x") != "y" {
+		t.Errorf("expected merged attrs, got: %v", attrsMap(m))
+	}
+
+	if !c.merge("b", attrs.NewCopyFrom(a), true) {
+		t.Errorf("expected uncached attrs to change")
+	}
+
+	if _, ok := c.items["a"]; ok {
+		t.Errorf("expected evicted attrs")
+	}
+}
@@ -0,0 +1,47 @@
+package star
+
+import (
+	guuid "github.com/google/uuid"
+	"github.com/milosgajdos/netscrape/pkg/uuid"
+)
+
+// UIDStrategy creates the uids of the entities mapped by scraper.
+type UIDStrategy interface {
+	// UID returns the uid of the entity of the given kind identified by key.
+	// kind is the name of the entity resource.
+	// key is GitHub node ID for repos, login for users
+	// and lowercase name for topics and langs.
+	UID(kind, key string) (uuid.UID, error)
+}
+
+// DefaultUIDs is the default uid strategy.
+// Repo uids are GitHub node IDs; the uids of
+// other entities are their keys suffixed with their kind.
+type DefaultUIDs struct{}
+
+// UID returns the uid of the entity of the given kind identified by key.
+func (DefaultUIDs) UID(kind, key string) (uuid.UID, error) {
+	if kind == repoRes {
+		return uuid.NewFromString(key)
+	}
+
+	return uuid.NewFromString(key + "-" + kind)
+}
+
+// NameUIDs is the uid strategy which creates deterministic
+// name-based UUIDv5 uids from entity kinds and keys in a namespace.
+type NameUIDs struct {
+	ns guuid.UUID
+}
+
+// NewNameUIDs creates a new name-based uid strategy in namespace ns and returns it.
+func NewNameUIDs(ns guuid.UUID) *NameUIDs {
+	return &NameUIDs{
+		ns: ns,
+	}
+}
+
+// UID returns the uid of the entity of the given kind identified by key.
+func (n *NameUIDs) UID(kind, key string) (uuid.UID, error) {
+	return uuid.NewFromString(guuid.NewSHA1(n.ns, []byte(kind+"/"+key)).String())
+}
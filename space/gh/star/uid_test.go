@@ -0,0 +1,141 @@
+package star
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	guuid "github.com/google/uuid"
+)
+
+func TestDefaultUIDs(t *testing.T) {
+	testCases := []struct {
+		kind string
+		key  string
+		want string
+	}{
+		{repoRes, "R0", "R0"},
+		{ownerRes, "bob", "bob-owner"},
+		{stargazerRes, "bob", "bob-stargazer"},
+		{topicRes, "graph", "graph-topic"},
+	}
+
+	for _, tc := range testCases {
+		uid, err := DefaultUIDs{}.UID(tc.kind, tc.key)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if uid.Value() != tc.want {
+			t.Errorf("expected %s uid: %s, got: %s", tc.kind, tc.want, uid.Value())
+		}
+	}
+}
+
+func TestNameUIDs(t *testing.T) {
+	ns := guuid.NewSHA1(guuid.NameSpaceURL, []byte("https://github.com"))
+
+	uids := NewNameUIDs(ns)
+
+	u1, err := uids.UID(ownerRes, "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u2, err := NewNameUIDs(ns).UID(ownerRes, "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if u1.Value() != u2.Value() {
+		t.Errorf("expected deterministic uid: %s, got: %s", u1.Value(), u2.Value())
+	}
+
+	id, err := guuid.Parse(u1.Value())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if id.Version() != 5 {
+		t.Errorf("expected UUID version: %d, got: %d", 5, id.Version())
+	}
+
+	u3, err := uids.UID(stargazerRes, "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if u1.Value() == u3.Value() {
+		t.Errorf("expected different uids of different kinds")
+	}
+
+	u4, err := NewNameUIDs(guuid.NameSpaceURL).UID(ownerRes, "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if u1.Value() == u4.Value() {
+		t.Errorf("expected different uids in different namespaces")
+	}
+}
+
+func TestMapUIDs(t *testing.T) {
+	ts := newTestServer([]testStar{newTestStar(0, "Owner", time.Now())})
+	defer ts.Close()
+
+	uids := NewNameUIDs(guuid.NameSpaceURL)
+
+	s, err := NewScraper(ts.client(), UIDs(uids), OwnerNamespaces(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	top, err := testMap(context.Background(), s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ents, err := top.Entities(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// NOTE: stargazer, repo, owner, topic and lang
+	if len(ents) != 5 {
+		t.Fatalf("expected entities: %d, got: %d", 5, len(ents))
+	}
+
+	for _, e := range ents {
+		if _, err := guuid.Parse(e.UID().Value()); err != nil {
+			t.Errorf("expected %s UUID, got: %s", e.Name(), e.UID().Value())
+		}
+
+		want := ns
+		if e.Resource().Name() == repoRes {
+			want = "owner"
+		}
+
+		if e.Namespace() != want {
+			t.Errorf("expected %s namespace: %s, got: %s", e.Name(), want, e.Namespace())
+		}
+	}
+
+	ownerUID, err := uids.UID(ownerRes, "Owner")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repoUID, err := uids.UID(repoRes, "R0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	links, err := top.Links(context.Background(), ownerUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(links) != 1 || links[0].To().Value() != repoUID.Value() {
+		t.Errorf("expected owner link to %s", repoUID.Value())
+	}
+}
@@ -0,0 +1,154 @@
+package dgraph
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/milosgajdos/netscrape/pkg/attrs"
+)
+
+// AttrType is attribute value type.
+type AttrType int
+
+const (
+	// StringAttr is string attribute
+	StringAttr AttrType = iota
+	// IntAttr is integer attribute
+	IntAttr
+	// FloatAttr is floating point attribute
+	FloatAttr
+	// BoolAttr is boolean attribute
+	BoolAttr
+	// DateTimeAttr is RFC3339 date time attribute
+	DateTimeAttr
+	// GeoAttr is GeoJSON attribute
+	GeoAttr
+)
+
+// String implements fmt.Stringer.
+// It returns the name of dgraph scalar type of the attribute.
+func (t AttrType) String() string {
+	switch t {
+	case IntAttr:
+		return "int"
+	case FloatAttr:
+		return "float"
+	case BoolAttr:
+		return "bool"
+	case DateTimeAttr:
+		return "datetime"
+	case GeoAttr:
+		return "geo"
+	default:
+		return "string"
+	}
+}
+
+// index returns dgraph index tokenizer of the attribute type.
+func (t AttrType) index() string {
+	switch t {
+	case IntAttr:
+		return "int"
+	case FloatAttr:
+		return "float"
+	case BoolAttr:
+		return "bool"
+	case DateTimeAttr:
+		return "hour"
+	case GeoAttr:
+		return "geo"
+	default:
+		return "exact"
+	}
+}
+
+// Attrs are dgraph node attributes.
+// Attributes are stored as strings unless their type is declared.
+type Attrs map[string]interface{}
+
+// AttrSchema returns DQL schema of the given typed attributes.
+// Every attribute is indexed so it can be used in query filters and ordering.
+func AttrSchema(types map[string]AttrType) string {
+	keys := make([]string, 0, len(types))
+	for k := range types {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	var b strings.Builder
+
+	for _, k := range keys {
+		t := types[k]
+		b.WriteString("\t" + k + ": " + t.String() + " @index(" + t.index() + ") .\n")
+	}
+
+	return b.String()
+}
+
+// typedAttrs returns a encoded as Attrs with values of the declared types.
+// Values which fail to be parsed as the declared type are stored as strings.
+func typedAttrs(a attrs.Attrs, types map[string]AttrType) Attrs {
+	if a == nil {
+		return nil
+	}
+
+	m := make(Attrs)
+
+	for _, k := range a.Keys() {
+		m[k] = typedValue(a.Get(k), types[k])
+	}
+
+	return m
+}
+
+// typedValue returns v parsed as type t.
+// It returns v if it fails to be parsed.
+func typedValue(v string, t AttrType) interface{} {
+	switch t {
+	case IntAttr:
+		if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return i
+		}
+	case FloatAttr:
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f
+		}
+	case BoolAttr:
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	case GeoAttr:
+		if json.Valid([]byte(v)) {
+			return json.RawMessage(v)
+		}
+	}
+
+	return v
+}
+
+// stringAttrs returns a with all values encoded as strings.
+func stringAttrs(a Attrs) map[string]string {
+	m := make(map[string]string, len(a))
+
+	for k, v := range a {
+		switch val := v.(type) {
+		case string:
+			m[k] = val
+		case float64:
+			m[k] = strconv.FormatFloat(val, 'f', -1, 64)
+		case bool:
+			m[k] = strconv.FormatBool(val)
+		default:
+			b, err := json.Marshal(val)
+			if err != nil {
+				continue
+			}
+			m[k] = string(b)
+		}
+	}
+
+	return m
+}
@@ -0,0 +1,135 @@
+package dgraph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/milosgajdos/netscrape/pkg/attrs"
+)
+
+func TestAttrSchema(t *testing.T) {
+	types := map[string]AttrType{
+		"stars":      IntAttr,
+		"starred_at": DateTimeAttr,
+	}
+
+	exp := "\tstarred_at: datetime @index(hour) .\n\tstars: int @index(int) .\n"
+
+	if s := AttrSchema(types); s != exp {
+		t.Errorf("expected schema: %q, got: %q", exp, s)
+	}
+}
+
+func TestTypedAttrs(t *testing.T) {
+	a, err := attrs.NewFromMap(map[string]string{
+		"stars":    "100",
+		"score":    "0.5",
+		"archived": "true",
+		"name":     "foo",
+		"invalid":  "bar",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	types := map[string]AttrType{
+		"stars":    IntAttr,
+		"score":    FloatAttr,
+		"archived": BoolAttr,
+		"invalid":  IntAttr,
+	}
+
+	b, err := json.Marshal(typedAttrs(a, types))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded Attrs
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := decoded["stars"].(float64); !ok {
+		t.Errorf("expected numeric stars, got: %T", decoded["stars"])
+	}
+
+	if _, ok := decoded["archived"].(bool); !ok {
+		t.Errorf("expected bool archived, got: %T", decoded["archived"])
+	}
+
+	for k, v := range stringAttrs(decoded) {
+		if a.Get(k) != v {
+			t.Errorf("expected attr %s val: %s, got: %s", k, a.Get(k), v)
+		}
+	}
+}
+
+func TestFindRequest(t *testing.T) {
+	s := &Store{
+		opts: Options{
+			AttrTypes: map[string]AttrType{"stars": IntAttr, "starred_at": DateTimeAttr},
+		},
+	}
+
+	opts := FindOptions{
+		Filters: []Filter{Gt("stars", "100"), Between("starred_at", "2020-01-01", "2021-01-01")},
+		OrderBy: "stars",
+		Desc:    true,
+		First:   10,
+	}
+
+	req, err := s.findRequest(context.Background(), opts, []string{"stars", "starred_at"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		`func: gt(stars, "100"), orderdesc: stars, first: 10`,
+		`@filter(between(starred_at, "2020-01-01", "2021-01-01"))`,
+		`attrs { stars starred_at }`,
+	} {
+		if !strings.Contains(req.Query, want) {
+			t.Errorf("expected query to contain: %s, got: %s", want, req.Query)
+		}
+	}
+
+	if _, err := s.findRequest(context.Background(), FindOptions{}, nil); !errors.Is(err, ErrInvalidQuery) {
+		t.Errorf("got: %v, want: %v", err, ErrInvalidQuery)
+	}
+
+	opts = FindOptions{Filters: []Filter{Eq("foo", "bar")}}
+	if _, err := s.findRequest(context.Background(), opts, nil); !errors.Is(err, ErrUnknownAttr) {
+		t.Errorf("got: %v, want: %v", err, ErrUnknownAttr)
+	}
+}
+
+func TestDecodeJSONFind(t *testing.T) {
+	data := []byte(`{
+		"find": [{
+			"~attrs": [{
+				"xid": "ent1/entNs",
+				"type": "Entity",
+				"name": "ent1",
+				"namespace": "entNs",
+				"resource": {"xid": "nodeResUID", "name": "nodeResName"},
+				"attrs": {"stars": 120}
+			}]
+		}]
+	}`)
+
+	ents, err := decodeJSONFind(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ents) != 1 {
+		t.Fatalf("expected entities: %d, got: %d", 1, len(ents))
+	}
+
+	if stars := ents[0].Attrs().Get("stars"); stars != "120" {
+		t.Errorf("expected stars: %s, got: %s", "120", stars)
+	}
+}
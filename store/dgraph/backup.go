@@ -0,0 +1,488 @@
+package dgraph
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	dgapi "github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/milosgajdos/netscrape/pkg/attrs"
+	"github.com/milosgajdos/netscrape/pkg/store"
+	"github.com/milosgajdos/netscrape/pkg/uuid"
+)
+
+const (
+	// BackupVersion is backup format version.
+	BackupVersion = 2
+	// DefaultPageSize is default number of nodes queried in a single request.
+	DefaultPageSize = 1000
+)
+
+const (
+	headerRecord   = "header"
+	resourceRecord = "resource"
+	entityRecord   = "entity"
+	linkRecord     = "link"
+	trailerRecord  = "trailer"
+)
+
+// Conflict is restore conflict policy.
+type Conflict int
+
+const (
+	// Overwrite overwrites existing entities.
+	Overwrite Conflict = iota
+	// Skip skips existing entities.
+	Skip
+	// Fail fails restore on the first existing entity.
+	Fail
+)
+
+// backupRecord is backup record.
+// Backups are gzip compressed streams of JSON records: the header
+// is followed by all the resources, then all the entities and their links.
+// The trailer records the number of backed up resources, entities and links.
+type backupRecord struct {
+	Kind      string        `json:"kind"`
+	Version   int           `json:"version,omitempty"`
+	CreatedAt *time.Time    `json:"created_at,omitempty"`
+	Resource  *Resource     `json:"resource,omitempty"`
+	Entity    *Entity       `json:"entity,omitempty"`
+	Link      *backupLink   `json:"link,omitempty"`
+	Counts    *backupCounts `json:"counts,omitempty"`
+}
+
+// backupCounts counts backup records.
+type backupCounts struct {
+	Resources int `json:"resources"`
+	Entities  int `json:"entities"`
+	Links     int `json:"links"`
+}
+
+// count counts record rec.
+func (c *backupCounts) count(rec *backupRecord) {
+	switch rec.Kind {
+	case resourceRecord:
+		c.Resources++
+	case entityRecord:
+		c.Entities++
+	case linkRecord:
+		c.Links++
+	}
+}
+
+// backupLink is backup link record.
+type backupLink struct {
+	From     string  `json:"from"`
+	To       string  `json:"to"`
+	Relation string  `json:"relation,omitempty"`
+	Weight   float64 `json:"weight,omitempty"`
+}
+
+// BackupOptions configure Backup.
+type BackupOptions struct {
+	// PageSize is the number of nodes queried in a single request.
+	PageSize int
+}
+
+// BackupOption is Backup option.
+type BackupOption func(*BackupOptions)
+
+// WithPageSize configures backup page size.
+func WithPageSize(n int) BackupOption {
+	return func(o *BackupOptions) {
+		o.PageSize = n
+	}
+}
+
+// RestoreOptions configure Restore.
+type RestoreOptions struct {
+	// Conflict is restore conflict policy.
+	Conflict Conflict
+}
+
+// RestoreOption is Restore option.
+type RestoreOption func(*RestoreOptions)
+
+// WithConflict configures restore conflict policy.
+func WithConflict(c Conflict) RestoreOption {
+	return func(o *RestoreOptions) {
+		o.Conflict = c
+	}
+}
+
+// Backup streams all the resources, entities and their links into w.
+// Backup reads the store from a snapshot so the backup is consistent
+// even if the store is being written to while the backup is in progress.
+// If Backup fails, the gzip stream is left unterminated and no trailer is written,
+// so the partial backup fails to restore.
+func (s *Store) Backup(ctx context.Context, w io.Writer, opts ...BackupOption) error {
+	bopts := BackupOptions{}
+	for _, apply := range opts {
+		apply(&bopts)
+	}
+
+	if bopts.PageSize <= 0 {
+		bopts.PageSize = DefaultPageSize
+	}
+
+	snap := s
+	if s.snap == nil {
+		var err error
+		snap, err = s.Snapshot(ctx)
+		if err != nil {
+			return err
+		}
+		defer snap.Close()
+	}
+
+	preds, err := snap.attrPredicates(ctx)
+	if err != nil {
+		return err
+	}
+
+	// NOTE: zw must not be closed on error so the partial backup is not terminated
+	zw := gzip.NewWriter(w)
+	enc := json.NewEncoder(zw)
+
+	var counts backupCounts
+
+	encode := func(rec *backupRecord) error {
+		if err := enc.Encode(rec); err != nil {
+			return err
+		}
+		counts.count(rec)
+		return nil
+	}
+
+	now := time.Now().UTC()
+	if err := enc.Encode(&backupRecord{Kind: headerRecord, Version: BackupVersion, CreatedAt: &now}); err != nil {
+		return fmt.Errorf("backup header: %w", err)
+	}
+
+	pages := []struct {
+		dtype  string
+		fields string
+		encode func(*node) error
+	}{
+		{
+			dtype:  "Resource",
+			fields: "xid type name group version kind namespaced" + attrsSelection(preds),
+			encode: func(n *node) error {
+				res := n.resource()
+				res.UID = ""
+				return encode(&backupRecord{Kind: resourceRecord, Resource: res})
+			},
+		},
+		{
+			dtype:  "Entity",
+			fields: "xid type name namespace resource { xid }" + attrsSelection(preds),
+			encode: func(n *node) error {
+				ent := n.Entity
+				ent.UID = ""
+				return encode(&backupRecord{Kind: entityRecord, Entity: &ent})
+			},
+		},
+		{
+			dtype:  "Entity",
+			fields: "xid links @facets(relation, weight) { xid }",
+			encode: func(n *node) error {
+				for _, l := range n.Links {
+					link := &backupLink{From: n.XID, To: l.XID, Relation: l.Relation, Weight: l.Weight}
+					if err := encode(&backupRecord{Kind: linkRecord, Link: link}); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+	}
+
+	for _, p := range pages {
+		if err := snap.page(ctx, p.dtype, p.fields, bopts.PageSize, p.encode); err != nil {
+			return fmt.Errorf("backup %s: %w", p.dtype, err)
+		}
+	}
+
+	if err := enc.Encode(&backupRecord{Kind: trailerRecord, Counts: &counts}); err != nil {
+		return fmt.Errorf("backup trailer: %w", err)
+	}
+
+	return zw.Close()
+}
+
+// node is dgraph node of either Entity or Resource type.
+type node struct {
+	Entity
+	Group      string `json:"group,omitempty"`
+	Version    string `json:"version,omitempty"`
+	Kind       string `json:"kind,omitempty"`
+	Namespaced bool   `json:"namespaced,omitempty"`
+}
+
+// resource returns Resource node of n.
+func (n *node) resource() *Resource {
+	return &Resource{
+		UID:        n.UID,
+		XID:        n.XID,
+		Type:       n.Type,
+		Name:       n.Name,
+		Group:      n.Group,
+		Version:    n.Version,
+		Kind:       n.Kind,
+		Namespaced: n.Namespaced,
+		Attrs:      n.Attrs,
+	}
+}
+
+// page queries all the nodes of the given dgraph type in pages of size n
+// and calls f for every node returned by the query.
+func (s *Store) page(ctx context.Context, dtype, fields string, n int, f func(*node) error) error {
+	after := ""
+
+	for {
+		q := `
+		{
+			page(func: type(` + dtype + `), first: ` + strconv.Itoa(n) + after + `) {
+				uid
+				` + fields + `
+			}
+		}
+		`
+
+		resp, err := s.do(ctx, QueryOp, &dgapi.Request{Query: q, ReadOnly: true})
+		if err != nil {
+			return fmt.Errorf("txn.Page: %w", err)
+		}
+
+		var result struct {
+			Page []*node `json:"page"`
+		}
+
+		if err := json.Unmarshal(resp.Json, &result); err != nil {
+			return fmt.Errorf("decode page: %w", err)
+		}
+
+		for _, node := range result.Page {
+			if err := f(node); err != nil {
+				return err
+			}
+		}
+
+		if len(result.Page) < n {
+			return nil
+		}
+
+		after = ", after: " + result.Page[len(result.Page)-1].UID
+	}
+}
+
+// attrPredicates returns the names of all attribute predicates stored in dgraph.
+// NOTE: attributes are stored as predicates of untyped nodes which can't be
+// expanded by expand(_all_) so they need to be queried explicitly.
+func (s *Store) attrPredicates(ctx context.Context) ([]string, error) {
+	resp, err := s.do(ctx, QueryOp, &dgapi.Request{Query: "schema {}", ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("txn.Schema: %w", err)
+	}
+
+	var result struct {
+		Schema []struct {
+			Predicate string `json:"predicate"`
+		} `json:"schema"`
+	}
+
+	if err := json.Unmarshal(resp.Json, &result); err != nil {
+		return nil, fmt.Errorf("decode schema: %w", err)
+	}
+
+	var preds []string
+
+	for _, p := range result.Schema {
+		if spacePredicates[p.Predicate] || strings.HasPrefix(p.Predicate, "dgraph.") {
+			continue
+		}
+		preds = append(preds, p.Predicate)
+	}
+
+	sort.Strings(preds)
+
+	return preds, nil
+}
+
+// attrsSelection returns attrs query selection of the given predicates.
+func attrsSelection(preds []string) string {
+	if len(preds) == 0 {
+		return ""
+	}
+
+	return " attrs { " + strings.Join(preds, " ") + " }"
+}
+
+// Restore replays the backup read from r into the store.
+// Existing entities are handled according to the configured conflict policy.
+// It returns error if the backup is malformed or its version is not supported.
+// Restore returns ErrUnsupportedBackup if the backup is truncated, i.e. if its trailer
+// is missing or the restored record counts do not match the counts in the trailer.
+// NOTE: the records read before the truncation are restored.
+func (s *Store) Restore(ctx context.Context, r io.Reader, opts ...RestoreOption) error {
+	ropts := RestoreOptions{}
+	for _, apply := range opts {
+		apply(&ropts)
+	}
+
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("restore: %w", err)
+	}
+	defer zr.Close()
+
+	dec := json.NewDecoder(bufio.NewReader(zr))
+
+	header := new(backupRecord)
+	if err := dec.Decode(header); err != nil {
+		return fmt.Errorf("restore header: %w", err)
+	}
+
+	if header.Kind != headerRecord || header.Version != BackupVersion {
+		return fmt.Errorf("restore version %d: %w", header.Version, ErrUnsupportedBackup)
+	}
+
+	resources := make(map[string]*Resource)
+
+	var counts backupCounts
+
+	for {
+		rec := new(backupRecord)
+		if err := dec.Decode(rec); err != nil {
+			switch err {
+			case io.EOF:
+				return fmt.Errorf("restore trailer: %w", ErrUnsupportedBackup)
+			case io.ErrUnexpectedEOF:
+				return fmt.Errorf("restore truncated record: %w", ErrUnsupportedBackup)
+			}
+			return fmt.Errorf("restore record: %w", err)
+		}
+
+		if rec.Kind == trailerRecord {
+			return restoreTrailer(dec, rec, counts)
+		}
+
+		if err := s.restore(ctx, rec, resources, ropts); err != nil {
+			return err
+		}
+
+		counts.count(rec)
+	}
+}
+
+// restoreTrailer checks the backup trailer rec matches the restored record counts
+// and that there are no records following it.
+func restoreTrailer(dec *json.Decoder, rec *backupRecord, counts backupCounts) error {
+	if rec.Counts == nil || *rec.Counts != counts {
+		return fmt.Errorf("restore trailer counts %+v, restored %+v: %w", rec.Counts, counts, ErrUnsupportedBackup)
+	}
+
+	if err := dec.Decode(new(backupRecord)); err != io.EOF {
+		return fmt.Errorf("restore record after trailer: %w", ErrUnsupportedBackup)
+	}
+
+	return nil
+}
+
+// restore restores a single backup record.
+// Restored resources are stored in resources so entities can be restored with them.
+func (s *Store) restore(ctx context.Context, rec *backupRecord, resources map[string]*Resource, opts RestoreOptions) error {
+	switch rec.Kind {
+	case resourceRecord:
+		if rec.Resource == nil {
+			return fmt.Errorf("restore resource: %w", ErrUnsupportedBackup)
+		}
+		resources[rec.Resource.XID] = rec.Resource
+
+		res, err := resourceToSpaceResource(rec.Resource)
+		if err != nil {
+			return err
+		}
+
+		return s.restoreEntity(ctx, res, opts)
+	case entityRecord:
+		if rec.Entity == nil || rec.Entity.Resource == nil {
+			return fmt.Errorf("restore entity: %w", ErrUnsupportedBackup)
+		}
+
+		res, ok := resources[rec.Entity.Resource.XID]
+		if !ok {
+			return fmt.Errorf("restore entity %s resource %s: %w", rec.Entity.XID, rec.Entity.Resource.XID, store.ErrEntityNotFound)
+		}
+		rec.Entity.Resource = res
+
+		ent, err := entityToSpaceEntity(rec.Entity)
+		if err != nil {
+			return err
+		}
+
+		return s.restoreEntity(ctx, ent, opts)
+	case linkRecord:
+		if rec.Link == nil {
+			return fmt.Errorf("restore link: %w", ErrUnsupportedBackup)
+		}
+
+		return s.restoreLink(ctx, rec.Link)
+	default:
+		return fmt.Errorf("restore record %q: %w", rec.Kind, ErrUnsupportedBackup)
+	}
+}
+
+// restoreEntity adds e to store handling the conflicts according to opts.
+func (s *Store) restoreEntity(ctx context.Context, e store.Entity, opts RestoreOptions) error {
+	if opts.Conflict != Overwrite {
+		_, err := s.Get(ctx, e.UID())
+		switch {
+		case err == nil:
+			if opts.Conflict == Skip {
+				return nil
+			}
+			return fmt.Errorf("restore %s: %w", e.UID().Value(), ErrConflict)
+		case err != store.ErrEntityNotFound:
+			return err
+		}
+	}
+
+	return s.Add(ctx, e)
+}
+
+// restoreLink links the entities of link l.
+func (s *Store) restoreLink(ctx context.Context, l *backupLink) error {
+	from, err := uuid.NewFromString(l.From)
+	if err != nil {
+		return err
+	}
+
+	to, err := uuid.NewFromString(l.To)
+	if err != nil {
+		return err
+	}
+
+	a, err := attrs.New()
+	if err != nil {
+		return err
+	}
+
+	if l.Relation != "" {
+		a.Set(attrs.Relation, l.Relation)
+	}
+
+	if l.Weight != 0 {
+		a.Set(attrs.Weight, strconv.FormatFloat(l.Weight, 'f', -1, 64))
+	}
+
+	return s.Link(ctx, from, to, store.WithAttrs(a))
+}
@@ -0,0 +1,125 @@
+package dgraph
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	dgapi "github.com/dgraph-io/dgo/v200/protos/api"
+)
+
+func newTestBackup(t *testing.T, records ...*backupRecord) *bytes.Buffer {
+	var buf bytes.Buffer
+
+	zw := gzip.NewWriter(&buf)
+	enc := json.NewEncoder(zw)
+
+	for _, rec := range records {
+		if err := enc.Encode(rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return &buf
+}
+
+func TestRestore(t *testing.T) {
+	var ops []Op
+
+	s := &Store{
+		opts: Options{
+			DryRun: true,
+			Recorder: RecorderFunc(func(op Op, req *dgapi.Request) error {
+				ops = append(ops, op)
+				return nil
+			}),
+		},
+	}
+
+	res := &Resource{XID: resUID, Name: resName, Group: resGroup, Version: resVersion, Kind: resKind}
+
+	backup := newTestBackup(t,
+		&backupRecord{Kind: headerRecord, Version: BackupVersion},
+		&backupRecord{Kind: resourceRecord, Resource: res},
+		&backupRecord{Kind: entityRecord, Entity: &Entity{XID: "ent1", Name: "ent1", Resource: &Resource{XID: resUID}}},
+		&backupRecord{Kind: entityRecord, Entity: &Entity{XID: "ent2", Name: "ent2", Resource: &Resource{XID: resUID}}},
+		&backupRecord{Kind: linkRecord, Link: &backupLink{From: "ent1", To: "ent2", Relation: "rel", Weight: 2.0}},
+		&backupRecord{Kind: trailerRecord, Counts: &backupCounts{Resources: 1, Entities: 2, Links: 1}},
+	)
+
+	if err := s.Restore(context.Background(), backup); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []Op{AddOp, AddOp, AddOp, LinkOp}
+
+	if len(ops) != len(exp) {
+		t.Fatalf("expected ops: %v, got: %v", exp, ops)
+	}
+
+	for i := range exp {
+		if ops[i] != exp[i] {
+			t.Errorf("expected op: %v, got: %v", exp[i], ops[i])
+		}
+	}
+}
+
+func TestRestoreErrors(t *testing.T) {
+	s := &Store{opts: Options{DryRun: true}}
+
+	testCases := []struct {
+		name    string
+		records []*backupRecord
+	}{
+		{"Version", []*backupRecord{{Kind: headerRecord, Version: BackupVersion + 1}}},
+		{"Header", []*backupRecord{{Kind: linkRecord}}},
+		{"Record", []*backupRecord{{Kind: headerRecord, Version: BackupVersion}, {Kind: "foo"}}},
+		{"NoTrailer", []*backupRecord{{Kind: headerRecord, Version: BackupVersion}}},
+		{"Counts", []*backupRecord{
+			{Kind: headerRecord, Version: BackupVersion},
+			{Kind: trailerRecord, Counts: &backupCounts{Resources: 1}},
+		}},
+		{"AfterTrailer", []*backupRecord{
+			{Kind: headerRecord, Version: BackupVersion},
+			{Kind: trailerRecord, Counts: &backupCounts{}},
+			{Kind: headerRecord, Version: BackupVersion},
+		}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			backup := newTestBackup(t, tc.records...)
+
+			if err := s.Restore(context.Background(), backup); !errors.Is(err, ErrUnsupportedBackup) {
+				t.Fatalf("got: %v, want: %v", err, ErrUnsupportedBackup)
+			}
+		})
+	}
+}
+
+func TestRestoreTruncated(t *testing.T) {
+	s := &Store{opts: Options{DryRun: true}}
+
+	backup := newTestBackup(t,
+		&backupRecord{Kind: headerRecord, Version: BackupVersion},
+		&backupRecord{Kind: trailerRecord, Counts: &backupCounts{}},
+	)
+
+	if err := s.Restore(context.Background(), bytes.NewReader(backup.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+
+	// NOTE: cutting off the gzip footer truncates the backup
+	truncated := backup.Bytes()[:backup.Len()-8]
+
+	if err := s.Restore(context.Background(), bytes.NewReader(truncated)); !errors.Is(err, ErrUnsupportedBackup) {
+		t.Fatalf("got: %v, want: %v", err, ErrUnsupportedBackup)
+	}
+}
@@ -0,0 +1,261 @@
+package dgraph
+
+import (
+	"strconv"
+	"strings"
+
+	dgapi "github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/milosgajdos/netscrape/pkg/space"
+	"github.com/milosgajdos/netscrape/pkg/store"
+	"github.com/milosgajdos/netscrape/pkg/uuid"
+)
+
+// mutation is a buffered store mutation.
+type mutation struct {
+	// op is mutation operation
+	op Op
+	// ent is entity added by AddOp
+	ent store.Entity
+	// uid is the uid of entity deleted by DelOp
+	uid uuid.UID
+	// from and to are linked by LinkOp and unlinked by UnlinkOp
+	from uuid.UID
+	to   uuid.UID
+	// opts are mutation options
+	opts store.Options
+	// retries is the number of times m has been retried
+	retries int
+}
+
+// xids returns xids of all the nodes touched by m.
+func (m mutation) xids() []string {
+	switch m.op {
+	case AddOp:
+		switch v := m.ent.(type) {
+		case space.Entity:
+			return []string{v.UID().Value(), v.Resource().UID().Value()}
+		case space.Resource:
+			return []string{v.UID().Value()}
+		}
+	case DelOp:
+		return []string{m.uid.Value()}
+	case LinkOp, UnlinkOp:
+		return []string{m.from.Value(), m.to.Value()}
+	}
+
+	return nil
+}
+
+// batch coalesces several mutations into a single upsert request.
+// Every xid is resolved by exactly one query block whose variable is shared
+// by all the mutations in the batch, so the nodes added in the batch can be
+// linked in the same request: dgraph creates a single new node for an empty
+// uid variable referenced by several mutations of the same request.
+type batch struct {
+	// cache looks cached xids up by their dgraph uids
+	cache *uidCache
+	// types are attribute types
+	types map[string]AttrType
+	// refs maps xids to dgraph uid references
+	refs map[string]string
+	// vars maps query variables to xids
+	vars map[string]string
+	// added contains xids of nodes set in the batch
+	added map[string]bool
+	// removed contains xids of nodes deleted or unlinked in the batch
+	removed map[string]bool
+	// query contains query blocks
+	query strings.Builder
+	// muts are batch mutations
+	muts []*dgapi.Mutation
+	// createdAt are mutations setting created_at of the nodes added in the batch
+	createdAt []*dgapi.Mutation
+	// size is the number of mutations in the batch
+	size int
+}
+
+func newBatch(cache *uidCache, types map[string]AttrType) *batch {
+	return &batch{
+		cache:   cache,
+		types:   types,
+		refs:    make(map[string]string),
+		vars:    make(map[string]string),
+		added:   make(map[string]bool),
+		removed: make(map[string]bool),
+	}
+}
+
+// conflicts returns true if m can not be coalesced with the mutations in b
+// without changing the order in which they are applied.
+// Removals never share a batch with any other mutation of the same xid
+// since dgraph does not guarantee the order of set and delete mutations.
+func (b *batch) conflicts(m mutation) bool {
+	for _, xid := range m.xids() {
+		if b.removed[xid] {
+			return true
+		}
+
+		if m.op == DelOp || m.op == UnlinkOp {
+			if _, ok := b.refs[xid]; ok {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// ref returns dgraph uid reference of the node with the given xid.
+// xid is referenced by a query variable which is created with a query block
+// resolving the node if it satisfies cond, unless the variable already exists.
+func (b *batch) ref(xid, cond string) string {
+	if ref, ok := b.refs[xid]; ok {
+		return ref
+	}
+
+	v := "v" + strconv.Itoa(len(b.vars))
+	b.vars[v] = xid
+	b.refs[xid] = "uid(" + v + ")"
+
+	b.query.WriteString(`
+		var(func: ` + xidFunc(b.cache, xid, cond) + ` {
+			` + v + ` as uid
+		}
+	`)
+
+	return b.refs[xid]
+}
+
+// exists returns dgraph condition which checks all the given xids
+// either exist in dgraph or are added in the batch.
+func (b *batch) exists(xids ...string) string {
+	var conds []string
+
+	for _, xid := range xids {
+		if v := refVar(b.refs[xid]); v != "" && !b.added[xid] {
+			conds = append(conds, "gt(len("+v+"), 0)")
+		}
+	}
+
+	return ifCond(conds...)
+}
+
+// add adds m to batch.
+// It returns error if m fails to be encoded into dgraph mutation.
+func (b *batch) add(m mutation) error {
+	var (
+		obj     interface{}
+		cond    string
+		created []string
+	)
+
+	switch m.op {
+	case AddOp:
+		switch v := m.ent.(type) {
+		case space.Entity:
+			e := b.ref(v.UID().Value(), "")
+			r := b.ref(v.Resource().UID().Value(), "")
+			created = b.created(v.UID().Value(), v.Resource().UID().Value())
+			b.added[v.UID().Value()] = true
+			b.added[v.Resource().UID().Value()] = true
+			obj = entityNode(v, e, r, b.types)
+		case space.Resource:
+			r := b.ref(v.UID().Value(), "")
+			created = b.created(v.UID().Value())
+			b.added[v.UID().Value()] = true
+			obj = resourceNode(v, r, b.types)
+		default:
+			return store.ErrUnsupported
+		}
+	case DelOp:
+		u := b.ref(m.uid.Value(), "NOT type(Resource) OR eq(count(~resource), 0)")
+		b.removed[m.uid.Value()] = true
+		obj = map[string]string{"uid": u}
+		cond = b.exists(m.uid.Value())
+	case LinkOp:
+		from := b.ref(m.from.Value(), "type(Entity)")
+		to := b.ref(m.to.Value(), "type(Entity)")
+		obj = linkNode(from, to, m.opts.Attrs)
+		cond = b.exists(m.from.Value(), m.to.Value())
+	case UnlinkOp:
+		from := b.ref(m.from.Value(), "type(Entity)")
+		to := b.ref(m.to.Value(), "type(Entity)")
+		b.removed[m.from.Value()] = true
+		b.removed[m.to.Value()] = true
+		obj = &Entity{
+			UID: from,
+			Links: []Entity{
+				{UID: to},
+			},
+		}
+		cond = b.exists(m.from.Value(), m.to.Value())
+	default:
+		return ErrUnknownOp
+	}
+
+	mu, err := MutationJSON(m.op, obj, cond)
+	if err != nil {
+		return err
+	}
+
+	b.muts = append(b.muts, mu)
+	b.size++
+
+	mutations, err := createdAtMutations(created...)
+	if err != nil {
+		return err
+	}
+
+	b.createdAt = append(b.createdAt, mutations...)
+
+	return nil
+}
+
+// created returns references of the given xids which have not been added in the batch yet.
+func (b *batch) created(xids ...string) []string {
+	var refs []string
+
+	for _, xid := range xids {
+		if !b.added[xid] {
+			refs = append(refs, b.refs[xid])
+		}
+	}
+
+	return refs
+}
+
+// request returns dgraph API request which executes all batch mutations.
+func (b *batch) request() *dgapi.Request {
+	return &dgapi.Request{
+		Query:     queryBlocks(b.query.String()),
+		Mutations: append(b.muts[:len(b.muts):len(b.muts)], b.createdAt...),
+		CommitNow: true,
+	}
+}
+
+// batches splits mutations into batches of at most size mutations.
+// Mutations are split whenever coalescing them would reorder them.
+// Cached xids are looked up by their dgraph uids.
+// Attributes are encoded with the given attribute types.
+func batches(muts []mutation, size int, cache *uidCache, types map[string]AttrType) ([]*batch, error) {
+	var bx []*batch
+
+	b := newBatch(cache, types)
+
+	for _, m := range muts {
+		if b.size >= size || b.conflicts(m) {
+			bx = append(bx, b)
+			b = newBatch(cache, types)
+		}
+
+		if err := b.add(m); err != nil {
+			return nil, err
+		}
+	}
+
+	if b.size > 0 {
+		bx = append(bx, b)
+	}
+
+	return bx, nil
+}
@@ -0,0 +1,162 @@
+package dgraph
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/milosgajdos/netscrape/pkg/store"
+)
+
+func TestBatches(t *testing.T) {
+	ent1, err := newTestEntity("ent1", "entNs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ent2, err := newTestEntity("ent2", "entNs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	add1 := mutation{op: AddOp, ent: ent1}
+	add2 := mutation{op: AddOp, ent: ent2}
+	link := mutation{op: LinkOp, from: ent1.UID(), to: ent2.UID()}
+	unlink := mutation{op: UnlinkOp, from: ent1.UID(), to: ent2.UID()}
+	del := mutation{op: DelOp, uid: ent1.UID()}
+
+	testCases := []struct {
+		name  string
+		muts  []mutation
+		size  int
+		count int
+	}{
+		{"Coalesce", []mutation{add1, add2, link}, 10, 1},
+		{"Size", []mutation{add1, add2, link}, 2, 2},
+		{"Unlink", []mutation{add1, add2, link, unlink}, 10, 2},
+		{"Delete", []mutation{add1, del, add1}, 10, 3},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			bx, err := batches(tc.muts, tc.size, nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(bx) != tc.count {
+				t.Fatalf("expected batches: %d, got: %d", tc.count, len(bx))
+			}
+
+			var n int
+			for _, b := range bx {
+				n += b.size
+				if got, want := len(b.request().Mutations), b.size+len(b.createdAt); got != want {
+					t.Errorf("expected request mutations: %d, got: %d", want, got)
+				}
+			}
+
+			if n != len(tc.muts) {
+				t.Errorf("expected mutations: %d, got: %d", len(tc.muts), n)
+			}
+		})
+	}
+}
+
+func TestBatchLinkCond(t *testing.T) {
+	ent1, err := newTestEntity("ent1", "entNs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ent2, err := newTestEntity("ent2", "entNs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := newBatch(nil, nil)
+
+	if err := b.add(mutation{op: AddOp, ent: ent1}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.add(mutation{op: LinkOp, from: ent1.UID(), to: ent2.UID()}); err != nil {
+		t.Fatal(err)
+	}
+
+	req := b.request()
+
+	// entity and resource of ent1 and ent2 should be resolved once
+	if c := strings.Count(req.Query, " as uid"); c != 3 {
+		t.Errorf("expected query variables: %d, got: %d", 3, c)
+	}
+
+	cond := req.Mutations[1].Cond
+	if want := "@if(gt(len(v2), 0))"; cond != want {
+		t.Errorf("expected cond: %s, got: %s", want, cond)
+	}
+}
+
+func TestBatchCachedCond(t *testing.T) {
+	ent1, err := newTestEntity("ent1", "entNs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ent2, err := newTestEntity("ent2", "entNs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cache := newUIDCache(10)
+	cache.Put(ent1.UID().Value(), "0x1")
+	cache.Put(ent2.UID().Value(), "0x2")
+
+	b := newBatch(cache, nil)
+
+	if err := b.add(mutation{op: LinkOp, from: ent1.UID(), to: ent2.UID()}); err != nil {
+		t.Fatal(err)
+	}
+
+	req := b.request()
+
+	// NOTE: cached nodes deleted by other clients must not be linked
+	if want := `var(func: uid(0x1)) @filter((eq(xid, "ent1/entNs")) AND (type(Entity)))`; !strings.Contains(req.Query, want) {
+		t.Errorf("expected query to contain: %s, got: %s", want, req.Query)
+	}
+
+	if cond, want := req.Mutations[0].Cond, "@if(gt(len(v0), 0) AND gt(len(v1), 0))"; cond != want {
+		t.Errorf("expected cond: %s, got: %s", want, cond)
+	}
+}
+
+func TestBatchUnsupported(t *testing.T) {
+	b := newBatch(nil, nil)
+
+	if err := b.add(mutation{op: AddOp}); err != store.ErrUnsupported {
+		t.Fatalf("got: %v, want: %v", err, store.ErrUnsupported)
+	}
+}
+
+func TestBatchCreatedAt(t *testing.T) {
+	ent1, err := newTestEntity("ent1", "entNs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := newBatch(nil, nil)
+
+	for i := 0; i < 2; i++ {
+		if err := b.add(mutation{op: AddOp, ent: ent1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// created_at is set once for both entity and its resource
+	if c := len(b.createdAt); c != 2 {
+		t.Fatalf("expected created_at mutations: %d, got: %d", 2, c)
+	}
+
+	if cond := b.createdAt[0].Cond; cond != "@if(eq(len(v0), 0))" {
+		t.Errorf("expected cond: %s, got: %s", "@if(eq(len(v0), 0))", cond)
+	}
+}
@@ -0,0 +1,419 @@
+package dgraph
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/milosgajdos/netscrape/pkg/space"
+	"github.com/milosgajdos/netscrape/pkg/store"
+	"github.com/milosgajdos/netscrape/pkg/uuid"
+)
+
+const (
+	// DefaultBufferSize is default number of buffered mutations which triggers flush.
+	DefaultBufferSize = 100
+	// DefaultFlushInterval is default buffer flush interval.
+	DefaultFlushInterval = time.Second
+	// DefaultMaxPending is default maximum number of buffered mutations in Buffer sizes.
+	DefaultMaxPending = 10
+)
+
+// BufferOptions configure Buffer.
+type BufferOptions struct {
+	// Size is the number of buffered mutations which triggers flush.
+	// It is also the maximum number of mutations sent in a single request.
+	Size int
+	// Interval is the interval between periodic flushes.
+	Interval time.Duration
+	// MaxPending is the maximum number of buffered mutations.
+	// Buffering blocks when MaxPending mutations are waiting to be flushed.
+	MaxPending int
+	// Retries is the number of times the mutations of a failed batch are retried.
+	Retries int
+	// ErrorHandler is called with every error returned by flush.
+	ErrorHandler func(error)
+}
+
+// BufferOption is Buffer option.
+type BufferOption func(*BufferOptions)
+
+// WithBufferSize configures Buffer size.
+func WithBufferSize(n int) BufferOption {
+	return func(o *BufferOptions) {
+		o.Size = n
+	}
+}
+
+// WithFlushInterval configures Buffer flush interval.
+func WithFlushInterval(d time.Duration) BufferOption {
+	return func(o *BufferOptions) {
+		o.Interval = d
+	}
+}
+
+// WithMaxPending configures the maximum number of buffered mutations.
+func WithMaxPending(n int) BufferOption {
+	return func(o *BufferOptions) {
+		o.MaxPending = n
+	}
+}
+
+// WithFlushRetries configures the number of times the mutations of a failed batch are retried.
+func WithFlushRetries(n int) BufferOption {
+	return func(o *BufferOptions) {
+		o.Retries = n
+	}
+}
+
+// WithErrorHandler configures Buffer flush error handler.
+func WithErrorHandler(f func(error)) BufferOption {
+	return func(o *BufferOptions) {
+		o.ErrorHandler = f
+	}
+}
+
+// Buffer is a write-behind buffer for dgraph Store.
+// Buffer queues mutations and periodically flushes them to Store
+// coalesced into batched upsert requests. Mutations are flushed
+// either when Size mutations are buffered or every Interval.
+// Mutations of the same xid are applied in the order they were buffered.
+// Buffering blocks when MaxPending mutations are waiting to be flushed.
+// If a batch fails, its mutations and all the mutations buffered after them
+// are requeued and retried by the next flush, unless they have already been
+// retried Retries times, in which case they are dropped after the error is reported.
+type Buffer struct {
+	s    *Store
+	opts BufferOptions
+	// muts are buffered mutations
+	muts []mutation
+	// closed is true once Buffer has been closed
+	closed bool
+	// mu synchronizes access to muts and closed
+	mu *sync.Mutex
+	// fmu serializes flushes
+	fmu *sync.Mutex
+	// slots limit the number of buffered mutations
+	slots chan struct{}
+	// flush triggers background flush
+	flush chan struct{}
+	// done stops background flushing
+	done chan struct{}
+	// cancel cancels background flush in progress
+	cancel context.CancelFunc
+	once   *sync.Once
+	wg     *sync.WaitGroup
+}
+
+// NewBuffer creates a new write-behind buffer for s and returns it.
+// NewBuffer starts a background goroutine which flushes the buffer;
+// Close must be called to stop it and flush the remaining mutations.
+func NewBuffer(s *Store, opts ...BufferOption) (*Buffer, error) {
+	bopts := BufferOptions{}
+	for _, apply := range opts {
+		apply(&bopts)
+	}
+
+	if bopts.Size <= 0 {
+		bopts.Size = DefaultBufferSize
+	}
+
+	if bopts.Interval <= 0 {
+		bopts.Interval = DefaultFlushInterval
+	}
+
+	if bopts.MaxPending <= 0 {
+		bopts.MaxPending = DefaultMaxPending * bopts.Size
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	b := &Buffer{
+		s:      s,
+		opts:   bopts,
+		mu:     &sync.Mutex{},
+		fmu:    &sync.Mutex{},
+		slots:  make(chan struct{}, bopts.MaxPending),
+		flush:  make(chan struct{}, 1),
+		done:   make(chan struct{}),
+		cancel: cancel,
+		once:   &sync.Once{},
+		wg:     &sync.WaitGroup{},
+	}
+
+	b.wg.Add(1)
+	go b.run(ctx)
+
+	return b, nil
+}
+
+// run flushes the buffer periodically or when triggered until Buffer is closed.
+// The flush in progress is aborted when ctx is canceled.
+func (b *Buffer) run(ctx context.Context) {
+	defer b.wg.Done()
+
+	ticker := time.NewTicker(b.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-b.flush:
+		case <-b.done:
+			return
+		}
+
+		// NOTE: errors are reported via ErrorHandler
+		_ = b.Flush(ctx)
+	}
+}
+
+// enqueue buffers m and triggers flush if the buffer is full.
+// It blocks until there is a space in the buffer or ctx is done.
+// It returns ErrBufferClosed if the buffer has been closed.
+func (b *Buffer) enqueue(ctx context.Context, m mutation) error {
+	select {
+	case b.slots <- struct{}{}:
+	default:
+		b.trigger()
+
+		select {
+		case b.slots <- struct{}{}:
+		case <-b.done:
+			return ErrBufferClosed
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		b.release(1)
+		return ErrBufferClosed
+	}
+	b.muts = append(b.muts, m)
+	full := len(b.muts) >= b.opts.Size
+	b.mu.Unlock()
+
+	if full {
+		b.trigger()
+	}
+
+	return nil
+}
+
+// trigger triggers background flush.
+func (b *Buffer) trigger() {
+	select {
+	case b.flush <- struct{}{}:
+	default:
+	}
+}
+
+// release releases n buffer slots.
+func (b *Buffer) release(n int) {
+	for i := 0; i < n; i++ {
+		<-b.slots
+	}
+}
+
+// Add buffers adding Entity to store.
+// It returns error if e is neither space.Entity nor space.Resource.
+func (b *Buffer) Add(ctx context.Context, e store.Entity, opts ...store.Option) error {
+	switch e.(type) {
+	case space.Entity, space.Resource:
+	default:
+		return store.ErrUnsupported
+	}
+
+	return b.enqueue(ctx, mutation{op: AddOp, ent: e, opts: storeOptions(opts...)})
+}
+
+// Get flushes the buffer and gets Entity from store.
+func (b *Buffer) Get(ctx context.Context, uid uuid.UID, opts ...store.Option) (store.Entity, error) {
+	if err := b.Flush(ctx); err != nil {
+		return nil, err
+	}
+
+	return b.s.Get(ctx, uid, opts...)
+}
+
+// Delete buffers deleting Entity from store.
+func (b *Buffer) Delete(ctx context.Context, uid uuid.UID, opts ...store.Option) error {
+	return b.enqueue(ctx, mutation{op: DelOp, uid: uid, opts: storeOptions(opts...)})
+}
+
+// Link buffers linking two entities in store.
+func (b *Buffer) Link(ctx context.Context, from, to uuid.UID, opts ...store.Option) error {
+	return b.enqueue(ctx, mutation{op: LinkOp, from: from, to: to, opts: storeOptions(opts...)})
+}
+
+// Unlink buffers unlinking two entities in store.
+func (b *Buffer) Unlink(ctx context.Context, from, to uuid.UID, opts ...store.Option) error {
+	return b.enqueue(ctx, mutation{op: UnlinkOp, from: from, to: to, opts: storeOptions(opts...)})
+}
+
+// Flush flushes all buffered mutations to store.
+// Every flush error is reported to ErrorHandler; Flush returns the first one.
+// The mutations of a failed batch which have not been retried Retries times
+// are requeued along with all the mutations buffered after them.
+func (b *Buffer) Flush(ctx context.Context) error {
+	b.fmu.Lock()
+	defer b.fmu.Unlock()
+
+	b.mu.Lock()
+	muts := b.muts
+	b.muts = nil
+	b.mu.Unlock()
+
+	if len(muts) == 0 {
+		return nil
+	}
+
+	// NOTE: deleted xids must not be resolved from cache
+	invalidate(b.s.cache, muts)
+
+	bx, err := batches(muts, b.opts.Size, b.s.cache, b.s.opts.AttrTypes)
+	if err != nil {
+		b.release(len(muts))
+		return b.report(err)
+	}
+
+	// NOTE: batches are recorded without uid cache, see Store.doCached
+	rbx := bx
+	if b.s.cache != nil && b.s.opts.Recorder != nil {
+		if rbx, err = batches(muts, b.opts.Size, nil, b.s.opts.AttrTypes); err != nil {
+			b.release(len(muts))
+			return b.report(err)
+		}
+	}
+
+	var ferr error
+
+	// NOTE: batches contain consecutive buffered mutations
+	off := 0
+
+	for i, batch := range bx {
+		bmuts := muts[off : off+batch.size]
+		off += batch.size
+
+		epoch := b.s.cache.Epoch()
+
+		resp, err := b.s.exec(ctx, BatchOp, batch.request(), rbx[i].request())
+		if err == nil {
+			b.s.cache.putUIDs(epoch, resp.Uids, batch.vars)
+			invalidate(b.s.cache, bmuts)
+			b.release(len(bmuts))
+			continue
+		}
+
+		err = b.report(fmt.Errorf("txn.Flush: %w", err))
+		if ferr == nil {
+			ferr = err
+		}
+
+		var retry []mutation
+		for _, m := range bmuts {
+			if m.retries < b.opts.Retries {
+				m.retries++
+				retry = append(retry, m)
+			}
+		}
+		b.release(len(bmuts) - len(retry))
+
+		if len(retry) > 0 {
+			b.requeue(append(retry, muts[off:]...))
+			break
+		}
+	}
+
+	return ferr
+}
+
+// invalidate removes the xids deleted by muts from cache.
+func invalidate(cache *uidCache, muts []mutation) {
+	for _, m := range muts {
+		if m.op == DelOp {
+			cache.Remove(m.uid.Value())
+		}
+	}
+}
+
+// requeue buffers muts in front of the buffered mutations.
+func (b *Buffer) requeue(muts []mutation) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.muts = append(muts, b.muts...)
+}
+
+// pending returns the number of buffered mutations.
+func (b *Buffer) pending() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.muts)
+}
+
+// report reports err to ErrorHandler and returns it.
+func (b *Buffer) report(err error) error {
+	if b.opts.ErrorHandler != nil {
+		b.opts.ErrorHandler(err)
+	}
+
+	return err
+}
+
+// Close stops background flushing and flushes the remaining mutations.
+// The remaining mutations are flushed until they are either stored or dropped
+// after being retried, or until ctx is done. Buffering fails once Buffer is closed.
+// If ctx is done before the background flush in progress finishes, the flush is aborted.
+// Close returns the last flush error. Closing closed Buffer does nothing.
+// Close does not close the underlying Store.
+func (b *Buffer) Close(ctx context.Context) error {
+	var err error
+
+	b.once.Do(func() {
+		defer b.cancel()
+
+		b.mu.Lock()
+		b.closed = true
+		b.mu.Unlock()
+
+		close(b.done)
+
+		stopped := make(chan struct{})
+		go func() {
+			b.wg.Wait()
+			close(stopped)
+		}()
+
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			err = ctx.Err()
+			return
+		}
+
+		for {
+			err = b.Flush(ctx)
+			if b.pending() == 0 || ctx.Err() != nil {
+				return
+			}
+		}
+	})
+
+	return err
+}
+
+// storeOptions returns store.Options configured with opts.
+func storeOptions(opts ...store.Option) store.Options {
+	sopts := store.Options{}
+	for _, apply := range opts {
+		apply(&sopts)
+	}
+
+	return sopts
+}
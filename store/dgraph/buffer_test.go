@@ -0,0 +1,234 @@
+package dgraph
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	dgapi "github.com/dgraph-io/dgo/v200/protos/api"
+)
+
+// newTestBuffer creates a new Buffer of dry-run store which records
+// the number of mutations of every flushed batch and fails them while fail returns true.
+func newTestBuffer(t *testing.T, fail func() bool, opts ...BufferOption) (*Buffer, func() []int) {
+	var (
+		mu      sync.Mutex
+		flushed []int
+	)
+
+	rec := RecorderFunc(func(op Op, req *dgapi.Request) error {
+		if fail() {
+			return errors.New("flush failed")
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		flushed = append(flushed, len(req.Mutations))
+		return nil
+	})
+
+	s := &Store{opts: Options{DryRun: true, Recorder: rec}}
+
+	// NOTE: buffer is only flushed explicitly
+	opts = append([]BufferOption{WithBufferSize(100), WithFlushInterval(time.Hour)}, opts...)
+
+	b, err := NewBuffer(s, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return b, func() []int {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return flushed
+	}
+}
+
+func TestBufferClose(t *testing.T) {
+	b, flushed := newTestBuffer(t, func() bool { return false })
+
+	ent, err := newTestEntity("ent1", "entNs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.Add(context.Background(), ent); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(flushed()) != 1 {
+		t.Errorf("expected flushed batches: %d, got: %d", 1, len(flushed()))
+	}
+
+	if err := b.Close(context.Background()); err != nil {
+		t.Errorf("expected closing closed buffer to succeed, got: %v", err)
+	}
+
+	if err := b.Add(context.Background(), ent); !errors.Is(err, ErrBufferClosed) {
+		t.Errorf("expected error: %v, got: %v", ErrBufferClosed, err)
+	}
+
+	if err := b.Link(context.Background(), ent.UID(), ent.UID()); !errors.Is(err, ErrBufferClosed) {
+		t.Errorf("expected error: %v, got: %v", ErrBufferClosed, err)
+	}
+}
+
+func TestBufferCloseContext(t *testing.T) {
+	var once sync.Once
+
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	defer close(unblock)
+
+	// NOTE: background flush blocks until the test finishes
+	b, _ := newTestBuffer(t, func() bool {
+		once.Do(func() { close(started) })
+		<-unblock
+		return false
+	}, WithFlushInterval(time.Millisecond))
+
+	ent, err := newTestEntity("ent1", "entNs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.Add(context.Background(), ent); err != nil {
+		t.Fatal(err)
+	}
+
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := b.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected error: %v, got: %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestBufferMaxPending(t *testing.T) {
+	b, _ := newTestBuffer(t, func() bool { return false }, WithMaxPending(1))
+
+	ent, err := newTestEntity("ent1", "entNs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// NOTE: flushing is blocked, so the buffer can't be drained
+	b.fmu.Lock()
+
+	if err := b.Add(context.Background(), ent); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if err := b.Add(ctx, ent); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected error: %v, got: %v", context.DeadlineExceeded, err)
+	}
+
+	b.fmu.Unlock()
+
+	if err := b.Add(context.Background(), ent); err != nil {
+		t.Errorf("expected drained buffer, got: %v", err)
+	}
+
+	if err := b.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBufferRetries(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		fails int
+	)
+
+	fail := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if fails > 0 {
+			fails--
+			return true
+		}
+		return false
+	}
+
+	setFails := func(n int) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		fails = n
+	}
+
+	var errs []error
+
+	b, flushed := newTestBuffer(t, fail, WithFlushRetries(1), WithErrorHandler(func(err error) {
+		errs = append(errs, err)
+	}))
+
+	ent1, err := newTestEntity("ent1", "entNs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ent2, err := newTestEntity("ent2", "entNs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.Add(context.Background(), ent1); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.Add(context.Background(), ent2); err != nil {
+		t.Fatal(err)
+	}
+
+	setFails(1)
+
+	if err := b.Flush(context.Background()); err == nil {
+		t.Fatal("expected flush error")
+	}
+
+	if p := b.pending(); p != 2 {
+		t.Fatalf("expected requeued mutations: %d, got: %d", 2, p)
+	}
+
+	if err := b.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := flushed(); len(got) != 1 || got[0] == 0 {
+		t.Errorf("expected single flushed batch, got: %v", got)
+	}
+
+	if err := b.Add(context.Background(), ent1); err != nil {
+		t.Fatal(err)
+	}
+
+	// NOTE: the mutation is dropped once it's been retried
+	setFails(2)
+
+	if err := b.Close(context.Background()); err == nil {
+		t.Fatal("expected close error")
+	}
+
+	if p := b.pending(); p != 0 {
+		t.Errorf("expected dropped mutations, got pending: %d", p)
+	}
+
+	if len(errs) != 3 {
+		t.Errorf("expected reported errors: %d, got: %d", 3, len(errs))
+	}
+}
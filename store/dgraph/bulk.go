@@ -0,0 +1,365 @@
+package dgraph
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/milosgajdos/netscrape/pkg/attrs"
+	"github.com/milosgajdos/netscrape/pkg/entity"
+	"github.com/milosgajdos/netscrape/pkg/space"
+	"github.com/milosgajdos/netscrape/pkg/store"
+	"github.com/milosgajdos/netscrape/pkg/uuid"
+)
+
+const (
+	// BulkRDFFile is the name of bulk loader RDF file written by WriteBulk.
+	BulkRDFFile = "space.rdf.gz"
+	// BulkSchemaFile is the name of bulk loader schema file written by WriteBulk.
+	BulkSchemaFile = "space.schema"
+	// BulkXIDMapFile is the name of xid map file written by WriteBulk.
+	BulkXIDMapFile = "xidmap.json"
+)
+
+// BulkOptions configure BulkWriter.
+type BulkOptions struct {
+	// AttrTypes are attribute types
+	AttrTypes map[string]AttrType
+	// CreatedAt is the creation time of written nodes
+	CreatedAt time.Time
+}
+
+// BulkOption is BulkWriter option.
+type BulkOption func(*BulkOptions)
+
+// WithBulkAttrTypes configures BulkWriter attribute types.
+func WithBulkAttrTypes(types map[string]AttrType) BulkOption {
+	return func(o *BulkOptions) {
+		o.AttrTypes = types
+	}
+}
+
+// WithCreatedAt configures the creation time of the nodes written by BulkWriter.
+func WithCreatedAt(t time.Time) BulkOption {
+	return func(o *BulkOptions) {
+		o.CreatedAt = t
+	}
+}
+
+// BulkWriter writes dgraph bulk loader input.
+// Nodes are written as gzip'd RDF N-Quads referencing blank nodes
+// which are mapped to xids in the xid map. The written data can be
+// loaded into a new dgraph cluster with dgraph bulk along with Schema.
+// BulkWriter is not safe for concurrent use.
+type BulkWriter struct {
+	zw   *gzip.Writer
+	opts BulkOptions
+	// createdAt is RDF literal of the creation time
+	createdAt string
+	// labels maps xids to blank node labels
+	labels map[string]string
+	// written contains xids of written nodes
+	written map[string]bool
+	// n is the number of blank nodes
+	n int
+	// err is the first write error
+	err error
+}
+
+// NewBulkWriter creates a new bulk loader writer which writes gzip'd RDF to w and returns it.
+// Close must be called to flush the written data.
+func NewBulkWriter(w io.Writer, opts ...BulkOption) (*BulkWriter, error) {
+	bopts := BulkOptions{}
+	for _, apply := range opts {
+		apply(&bopts)
+	}
+
+	if bopts.CreatedAt.IsZero() {
+		bopts.CreatedAt = time.Now()
+	}
+
+	return &BulkWriter{
+		zw:        gzip.NewWriter(w),
+		opts:      bopts,
+		createdAt: bopts.CreatedAt.UTC().Format(time.RFC3339),
+		labels:    make(map[string]string),
+		written:   make(map[string]bool),
+	}, nil
+}
+
+// label returns blank node label of xid.
+func (b *BulkWriter) label(xid string) string {
+	if l, ok := b.labels[xid]; ok {
+		return l
+	}
+
+	b.n++
+	b.labels[xid] = "_:n" + strconv.Itoa(b.n)
+
+	return b.labels[xid]
+}
+
+// quad writes a single N-Quad.
+// NOTE: write errors are recorded and returned by the next Add, Link or Close.
+func (b *BulkWriter) quad(subject, pred, object string) {
+	if b.err != nil {
+		return
+	}
+
+	_, b.err = io.WriteString(b.zw, subject+" <"+pred+"> "+object+" .\n")
+}
+
+// Add writes e as bulk loader nodes.
+// Entity resources are written along with entities unless they have already been written.
+// Entities which have already been written are ignored.
+// It returns error if e is neither space.Entity nor space.Resource.
+func (b *BulkWriter) Add(ctx context.Context, e store.Entity, opts ...store.Option) error {
+	switch v := e.(type) {
+	case space.Entity:
+		b.addEntity(v)
+	case space.Resource:
+		b.addResource(v)
+	default:
+		return store.ErrUnsupported
+	}
+
+	return b.err
+}
+
+// addResource writes resource node r.
+func (b *BulkWriter) addResource(r space.Resource) string {
+	xid := r.UID().Value()
+	l := b.label(xid)
+
+	if b.written[xid] {
+		return l
+	}
+
+	b.written[xid] = true
+
+	b.node(l, xid, r.Type(), r.Name(), r.Attrs())
+	b.quad(l, "group", literal(r.Group()))
+	b.quad(l, "version", literal(r.Version()))
+	b.quad(l, "kind", literal(r.Kind()))
+	b.quad(l, "namespaced", typedLiteral(strconv.FormatBool(r.Namespaced()), BoolAttr))
+
+	return l
+}
+
+// addEntity writes entity node e.
+func (b *BulkWriter) addEntity(e space.Entity) {
+	xid := e.UID().Value()
+	l := b.label(xid)
+
+	if b.written[xid] {
+		return
+	}
+
+	b.written[xid] = true
+
+	b.node(l, xid, e.Type(), e.Name(), e.Attrs())
+	b.quad(l, "namespace", literal(e.Namespace()))
+	b.quad(l, "resource", b.addResource(e.Resource()))
+}
+
+// node writes the predicates shared by all nodes.
+func (b *BulkWriter) node(l, xid string, t entity.Type, name string, a attrs.Attrs) {
+	b.quad(l, "dgraph.type", literal(t.String()))
+	b.quad(l, "xid", literal(xid))
+	b.quad(l, "type", literal(t.String()))
+	b.quad(l, "name", literal(name))
+	b.quad(l, "created_at", typedLiteral(b.createdAt, DateTimeAttr))
+
+	if a == nil || len(a.Keys()) == 0 {
+		return
+	}
+
+	al := l + "_attrs"
+	b.quad(l, "attrs", al)
+
+	keys := a.Keys()
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		b.quad(al, k, typedLiteral(a.Get(k), b.opts.AttrTypes[k]))
+	}
+}
+
+// Link writes link between from and to entities.
+// Link relation and weight are read from the link attributes.
+// It returns store.ErrEntityNotFound if either of the entities has not been written.
+func (b *BulkWriter) Link(ctx context.Context, from, to uuid.UID, opts ...store.Option) error {
+	if !b.written[from.Value()] || !b.written[to.Value()] {
+		return store.ErrEntityNotFound
+	}
+
+	node := linkNode(b.labels[from.Value()], b.labels[to.Value()], storeOptions(opts...).Attrs)
+	link := node.Links[0]
+
+	facets := "(relation=" + literal(link.Relation) + ", weight=" + strconv.FormatFloat(link.Weight, 'f', -1, 64) + ")"
+
+	b.quad(node.UID, "links", link.UID+" "+facets)
+
+	return b.err
+}
+
+// WriteTop writes all entities of top along with their links.
+func (b *BulkWriter) WriteTop(ctx context.Context, top space.Top) error {
+	ents, err := top.Entities(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range ents {
+		if err := b.Add(ctx, e); err != nil {
+			return fmt.Errorf("bulk add %s: %w", e.UID(), err)
+		}
+	}
+
+	for _, e := range ents {
+		links, err := top.Links(ctx, e.UID())
+		if err != nil {
+			// NOTE: entities without links are not found in top links
+			if errors.Is(err, space.ErrEntityNotFound) {
+				continue
+			}
+			return err
+		}
+
+		for _, l := range links {
+			if err := b.Link(ctx, l.From(), l.To(), store.WithAttrs(l.Attrs())); err != nil {
+				return fmt.Errorf("bulk link %s: %w", l.UID(), err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Schema writes bulk loader schema to w.
+// The schema consists of SpaceDQLSchema and the schema of typed attributes.
+func (b *BulkWriter) Schema(w io.Writer) error {
+	_, err := io.WriteString(w, SpaceDQLSchema+AttrSchema(b.opts.AttrTypes))
+	return err
+}
+
+// XIDMap writes JSON map of the written xids to their blank node labels to w.
+func (b *BulkWriter) XIDMap(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+
+	return enc.Encode(b.labels)
+}
+
+// Close flushes the written data.
+// It does not close the underlying writer.
+func (b *BulkWriter) Close() error {
+	if err := b.zw.Close(); err != nil && b.err == nil {
+		b.err = err
+	}
+
+	return b.err
+}
+
+// WriteBulk writes bulk loader input of top to dir.
+// It writes BulkRDFFile, BulkSchemaFile and BulkXIDMapFile;
+// dir is created if it does not exist.
+func WriteBulk(ctx context.Context, dir string, top space.Top, opts ...BulkOption) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	f, err := os.Create(filepath.Join(dir, BulkRDFFile))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	b, err := NewBulkWriter(f, opts...)
+	if err != nil {
+		return err
+	}
+
+	if err := b.WriteTop(ctx, top); err != nil {
+		return err
+	}
+
+	if err := b.Close(); err != nil {
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	for name, write := range map[string]func(io.Writer) error{
+		BulkSchemaFile: b.Schema,
+		BulkXIDMapFile: b.XIDMap,
+	} {
+		if err := writeFile(filepath.Join(dir, name), write); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeFile creates file with the given path and writes it with write.
+func writeFile(path string, write func(io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// rdfEscaper escapes RDF string literals.
+var rdfEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+
+// literal returns RDF string literal of s.
+func literal(s string) string {
+	return `"` + rdfEscaper.Replace(s) + `"`
+}
+
+// typedLiteral returns RDF literal of v with the type t.
+// Values which fail to be parsed as t are written as string literals.
+func typedLiteral(v string, t AttrType) string {
+	var rdfType string
+
+	switch t {
+	case IntAttr:
+		rdfType = "xs:int"
+	case FloatAttr:
+		rdfType = "xs:float"
+	case BoolAttr:
+		rdfType = "xs:boolean"
+	case DateTimeAttr:
+		if _, err := time.Parse(time.RFC3339, v); err == nil {
+			return literal(v) + "^^<xs:dateTime>"
+		}
+	case GeoAttr:
+		rdfType = "geo:geojson"
+	}
+
+	// NOTE: typedValue returns v as is if it fails to be parsed
+	if _, ok := typedValue(v, t).(string); ok || rdfType == "" {
+		return literal(v)
+	}
+
+	return literal(v) + "^^<" + rdfType + ">"
+}
@@ -0,0 +1,189 @@
+package dgraph
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/milosgajdos/netscrape/pkg/attrs"
+	"github.com/milosgajdos/netscrape/pkg/space/top"
+	"github.com/milosgajdos/netscrape/pkg/store"
+)
+
+func newTestTop() (*top.Top, error) {
+	t, err := top.New()
+	if err != nil {
+		return nil, err
+	}
+
+	ent1, err := newTestEntity("ent1", "entNs")
+	if err != nil {
+		return nil, err
+	}
+
+	ent1.Attrs().Set("stars", "100")
+
+	ent2, err := newTestEntity("ent2", "entNs")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := t.Add(context.Background(), ent1); err != nil {
+		return nil, err
+	}
+
+	if err := t.Add(context.Background(), ent2); err != nil {
+		return nil, err
+	}
+
+	if err := t.Link(context.Background(), ent1.UID(), ent2.UID()); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func TestBulkWriter(t *testing.T) {
+	tp, err := newTestTop()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+
+	createdAt := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	b, err := NewBulkWriter(&buf, WithBulkAttrTypes(map[string]AttrType{"stars": IntAttr}), WithCreatedAt(createdAt))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.WriteTop(context.Background(), tp); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rdf := string(data)
+
+	var xids map[string]string
+
+	var xbuf bytes.Buffer
+	if err := b.XIDMap(&xbuf); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := json.Unmarshal(xbuf.Bytes(), &xids); err != nil {
+		t.Fatal(err)
+	}
+
+	// ent1, ent2 and their shared resource
+	if len(xids) != 3 {
+		t.Fatalf("expected xids: %d, got: %d", 3, len(xids))
+	}
+
+	ent1, ent2, res := xids["ent1/entNs"], xids["ent2/entNs"], xids[resUID]
+
+	for _, want := range []string{
+		ent1 + ` <xid> "ent1/entNs" .`,
+		ent1 + ` <resource> ` + res + ` .`,
+		ent1 + ` <created_at> "2021-03-01T00:00:00Z"^^<xs:dateTime> .`,
+		ent1 + `_attrs <stars> "100"^^<xs:int> .`,
+		ent1 + ` <links> ` + ent2 + ` (relation="Unknown", weight=1) .`,
+		res + ` <dgraph.type> "Resource" .`,
+		res + ` <namespaced> "true"^^<xs:boolean> .`,
+	} {
+		if !strings.Contains(rdf, want+"\n") {
+			t.Errorf("expected RDF to contain: %s, got:\n%s", want, rdf)
+		}
+	}
+
+	if c := strings.Count(rdf, `<dgraph.type> "Resource"`); c != 1 {
+		t.Errorf("expected resources: %d, got: %d", 1, c)
+	}
+
+	var sbuf bytes.Buffer
+	if err := b.Schema(&sbuf); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(sbuf.String(), "stars: int @index(int) .") {
+		t.Errorf("expected schema to contain stars, got: %s", sbuf.String())
+	}
+}
+
+func TestBulkWriterErrors(t *testing.T) {
+	b, err := NewBulkWriter(ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.Add(context.Background(), nil); err != store.ErrUnsupported {
+		t.Errorf("got: %v, want: %v", err, store.ErrUnsupported)
+	}
+
+	ent1, err := newTestEntity("ent1", "entNs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ent2, err := newTestEntity("ent2", "entNs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.Add(context.Background(), ent1); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := attrs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.Link(context.Background(), ent1.UID(), ent2.UID(), store.WithAttrs(a)); err != store.ErrEntityNotFound {
+		t.Errorf("got: %v, want: %v", err, store.ErrEntityNotFound)
+	}
+}
+
+func TestWriteBulk(t *testing.T) {
+	tp, err := newTestTop()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "bulk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := WriteBulk(context.Background(), dir, tp); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{BulkRDFFile, BulkSchemaFile, BulkXIDMapFile} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("expected file %s: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package dgraph
+
+import (
+	"container/list"
+	"encoding/json"
+	"strings"
+	"sync"
+)
+
+// uidCache is LRU cache of xid to dgraph uid mappings.
+// Every removal advances the cache epoch, so the uids read by requests
+// which started before an xid was removed are not cached again.
+// NOTE: all uidCache methods are safe to call on nil cache.
+type uidCache struct {
+	// size is the maximum number of cached uids
+	size int
+	// epoch is the number of removals
+	epoch uint64
+	// ll is LRU list
+	ll *list.List
+	// items indexes ll elements by xid
+	items map[string]*list.Element
+	// mu synchronizes access to cache
+	mu *sync.Mutex
+}
+
+// cacheItem is uidCache item.
+type cacheItem struct {
+	xid string
+	uid string
+}
+
+// newUIDCache creates a new uid cache with the given size and returns it.
+func newUIDCache(size int) *uidCache {
+	return &uidCache{
+		size:  size,
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+		mu:    &sync.Mutex{},
+	}
+}
+
+// Get returns dgraph uid of the given xid.
+// It returns false if the xid is not cached.
+func (c *uidCache) Get(xid string) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[xid]
+	if !ok {
+		return "", false
+	}
+
+	c.ll.MoveToFront(el)
+
+	return el.Value.(*cacheItem).uid, true
+}
+
+// Epoch returns the current cache epoch.
+func (c *uidCache) Epoch() uint64 {
+	if c == nil {
+		return 0
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.epoch
+}
+
+// Put caches dgraph uid of the given xid.
+// If the cache is full the least recently used uid is evicted.
+func (c *uidCache) Put(xid, uid string) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.put(xid, uid)
+}
+
+// PutAt caches dgraph uid of the given xid read by a request which started at the given epoch.
+// The uid is not cached if any xid has been removed from cache since.
+func (c *uidCache) PutAt(epoch uint64, xid, uid string) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.epoch != epoch {
+		return
+	}
+
+	c.put(xid, uid)
+}
+
+// put caches dgraph uid of the given xid.
+// NOTE: put must be called with c.mu held.
+func (c *uidCache) put(xid, uid string) {
+	if xid == "" || uid == "" {
+		return
+	}
+
+	if el, ok := c.items[xid]; ok {
+		el.Value.(*cacheItem).uid = uid
+		c.ll.MoveToFront(el)
+		return
+	}
+
+	c.items[xid] = c.ll.PushFront(&cacheItem{xid: xid, uid: uid})
+
+	if c.ll.Len() > c.size {
+		el := c.ll.Back()
+		c.ll.Remove(el)
+		delete(c.items, el.Value.(*cacheItem).xid)
+	}
+}
+
+// Remove removes xid from cache and advances the cache epoch.
+func (c *uidCache) Remove(xid string) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.epoch++
+
+	if el, ok := c.items[xid]; ok {
+		c.ll.Remove(el)
+		delete(c.items, xid)
+	}
+}
+
+// Len returns the number of cached uids.
+func (c *uidCache) Len() int {
+	if c == nil {
+		return 0
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.ll.Len()
+}
+
+// putUIDs caches the uids of new nodes returned in response to mutation which started at the given epoch.
+// uids keys are dgraph uid variables and vars maps the variables to xids.
+func (c *uidCache) putUIDs(epoch uint64, uids map[string]string, vars map[string]string) {
+	if c == nil {
+		return
+	}
+
+	for v, uid := range uids {
+		v = strings.TrimSuffix(strings.TrimPrefix(v, "uid("), ")")
+		if xid, ok := vars[v]; ok {
+			c.PutAt(epoch, xid, uid)
+		}
+	}
+}
+
+// putJSON caches xid to uid mappings of all the nodes found in JSON response b
+// to the request which started at the given epoch.
+// Nodes without either xid or uid are ignored.
+func (c *uidCache) putJSON(epoch uint64, b []byte) {
+	if c == nil || len(b) == 0 {
+		return
+	}
+
+	var result map[string]interface{}
+	if err := json.Unmarshal(b, &result); err != nil {
+		return
+	}
+
+	var walk func(v interface{})
+
+	walk = func(v interface{}) {
+		switch n := v.(type) {
+		case map[string]interface{}:
+			xid, _ := n["xid"].(string)
+			uid, _ := n["uid"].(string)
+			c.PutAt(epoch, xid, uid)
+			for _, val := range n {
+				walk(val)
+			}
+		case []interface{}:
+			for _, val := range n {
+				walk(val)
+			}
+		}
+	}
+
+	walk(result)
+}
@@ -0,0 +1,130 @@
+package dgraph
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestUIDCache(t *testing.T) {
+	c := newUIDCache(2)
+
+	c.Put("a", "0x1")
+	c.Put("b", "0x2")
+
+	if uid, ok := c.Get("a"); !ok || uid != "0x1" {
+		t.Fatalf("expected uid: %s, got: %s", "0x1", uid)
+	}
+
+	// b is the least recently used uid
+	c.Put("c", "0x3")
+
+	if _, ok := c.Get("b"); ok {
+		t.Errorf("expected %s to be evicted", "b")
+	}
+
+	if c.Len() != 2 {
+		t.Errorf("expected cache len: %d, got: %d", 2, c.Len())
+	}
+
+	c.Remove("a")
+
+	if _, ok := c.Get("a"); ok {
+		t.Errorf("expected %s to be removed", "a")
+	}
+}
+
+func TestUIDCacheNil(t *testing.T) {
+	var c *uidCache
+
+	c.Put("a", "0x1")
+	c.Remove("a")
+
+	if _, ok := c.Get("a"); ok {
+		t.Errorf("expected nil cache miss")
+	}
+
+	if c.Len() != 0 {
+		t.Errorf("expected cache len: %d, got: %d", 0, c.Len())
+	}
+}
+
+func TestUIDCachePut(t *testing.T) {
+	c := newUIDCache(10)
+
+	c.putUIDs(c.Epoch(), map[string]string{"uid(e)": "0x1", "uid(x)": "0x2"}, map[string]string{"e": "ent"})
+
+	if uid, ok := c.Get("ent"); !ok || uid != "0x1" {
+		t.Errorf("expected uid: %s, got: %s", "0x1", uid)
+	}
+
+	c.putJSON(c.Epoch(), []byte(`{"entity": [{"uid": "0x3", "xid": "ent2", "links": [{"uid": "0x4", "xid": "ent3"}]}]}`))
+
+	for xid, want := range map[string]string{"ent2": "0x3", "ent3": "0x4"} {
+		if uid, ok := c.Get(xid); !ok || uid != want {
+			t.Errorf("expected uid: %s, got: %s", want, uid)
+		}
+	}
+}
+
+func TestUIDCacheEpoch(t *testing.T) {
+	c := newUIDCache(10)
+
+	epoch := c.Epoch()
+
+	// NOTE: the request which started at epoch read the uid of a deleted node
+	c.Remove("ent")
+
+	c.putJSON(epoch, []byte(`{"entity": [{"uid": "0x1", "xid": "ent"}]}`))
+	c.PutAt(epoch, "ent2", "0x2")
+
+	if c.Len() != 0 {
+		t.Errorf("expected stale uids not to be cached, got: %d", c.Len())
+	}
+
+	c.PutAt(c.Epoch(), "ent2", "0x2")
+
+	if uid, ok := c.Get("ent2"); !ok || uid != "0x2" {
+		t.Errorf("expected uid: %s, got: %s", "0x2", uid)
+	}
+}
+
+func TestCachedLinkRequest(t *testing.T) {
+	ent1, err := newTestEntity("ent1", "entNs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ent2, err := newTestEntity("ent2", "entNs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &Store{cache: newUIDCache(10)}
+
+	s.cache.Put(ent1.UID().Value(), "0x1")
+	s.cache.Put(ent2.UID().Value(), "0x2")
+
+	req, err := s.linkRequest(context.Background(), ent1.UID(), ent2.UID())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// NOTE: cached nodes are looked up by uid, but must still exist
+	for _, want := range []string{
+		`var(func: uid(0x1)) @filter((eq(xid, "ent1/entNs")) AND (type(Entity)))`,
+		`var(func: uid(0x2)) @filter((eq(xid, "ent2/entNs")) AND (type(Entity)))`,
+	} {
+		if !strings.Contains(req.Query, want) {
+			t.Errorf("expected query to contain: %s, got: %s", want, req.Query)
+		}
+	}
+
+	if strings.Contains(req.Query, "func: eq(xid") {
+		t.Errorf("expected no xid index lookups, got: %s", req.Query)
+	}
+
+	if cond, want := req.Mutations[0].Cond, "@if(gt(len(from), 0) AND gt(len(to), 0))"; cond != want {
+		t.Errorf("expected cond: %s, got: %s", want, cond)
+	}
+}
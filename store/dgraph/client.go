@@ -2,9 +2,11 @@ package dgraph
 
 import (
 	"context"
+	"fmt"
 
 	dgo "github.com/dgraph-io/dgo/v200"
 	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/milosgajdos/netscrape/pkg/store"
 	"google.golang.org/grpc"
 )
 
@@ -18,6 +20,8 @@ type Auth struct {
 type Client struct {
 	*dgo.Dgraph
 	conn *grpc.ClientConn
+	// auth is true if the client is logged in
+	auth bool
 }
 
 // NewClient creates a new dgraph client and returns it.
@@ -52,9 +56,21 @@ func NewClient(target string, opts ...Option) (*Client, error) {
 	return &Client{
 		Dgraph: dg,
 		conn:   conn,
+		auth:   dopts.Auth != nil,
 	}, nil
 }
 
+// queryAt executes read-only req at req.StartTs outside of any transaction.
+// NOTE: dgo does not expose neither transactions with a given start timestamp
+// nor its ACL credentials, so it returns store.ErrUnsupported if the client is logged in.
+func (c *Client) queryAt(ctx context.Context, req *api.Request) (*api.Response, error) {
+	if c.auth {
+		return nil, fmt.Errorf("query at %d with ACL: %w", req.StartTs, store.ErrUnsupported)
+	}
+
+	return api.NewDgraphClient(c.conn).Query(ctx, req)
+}
+
 // Close closes dgraph connection.
 func (c *Client) Close() error {
 	return c.conn.Close()
@@ -4,4 +4,16 @@ import "errors"
 
 var (
 	ErrUnknownOp = errors.New("ErrUnknownOp")
+	// ErrSnapshotReadOnly is returned when mutating snapshot store
+	ErrSnapshotReadOnly = errors.New("ErrSnapshotReadOnly")
+	// ErrUnsupportedBackup is returned when restoring malformed or unsupported backup
+	ErrUnsupportedBackup = errors.New("ErrUnsupportedBackup")
+	// ErrConflict is returned when restoring entity which already exists
+	ErrConflict = errors.New("ErrConflict")
+	// ErrUnknownAttr is returned when querying attribute of unknown type
+	ErrUnknownAttr = errors.New("ErrUnknownAttr")
+	// ErrInvalidQuery is returned when query options are invalid
+	ErrInvalidQuery = errors.New("ErrInvalidQuery")
+	// ErrBufferClosed is returned when buffering mutations in closed Buffer
+	ErrBufferClosed = errors.New("ErrBufferClosed")
 )
@@ -0,0 +1,243 @@
+package dgraph
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	dgapi "github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/milosgajdos/netscrape/pkg/store"
+)
+
+// Filter is typed attribute query filter.
+type Filter struct {
+	// Attr is attribute name
+	Attr string
+	// Func is dgraph comparison function
+	Func string
+	// Args are function arguments
+	Args []string
+}
+
+// dql returns DQL function of the filter.
+func (f Filter) dql() string {
+	args := make([]string, len(f.Args))
+	for i, a := range f.Args {
+		args[i] = strconv.Quote(a)
+	}
+
+	return f.Func + "(" + f.Attr + ", " + strings.Join(args, ", ") + ")"
+}
+
+// Eq returns filter which matches attributes equal to v.
+func Eq(attr, v string) Filter {
+	return Filter{Attr: attr, Func: "eq", Args: []string{v}}
+}
+
+// Gt returns filter which matches attributes greater than v.
+func Gt(attr, v string) Filter {
+	return Filter{Attr: attr, Func: "gt", Args: []string{v}}
+}
+
+// Ge returns filter which matches attributes greater than or equal to v.
+func Ge(attr, v string) Filter {
+	return Filter{Attr: attr, Func: "ge", Args: []string{v}}
+}
+
+// Lt returns filter which matches attributes less than v.
+func Lt(attr, v string) Filter {
+	return Filter{Attr: attr, Func: "lt", Args: []string{v}}
+}
+
+// Le returns filter which matches attributes less than or equal to v.
+func Le(attr, v string) Filter {
+	return Filter{Attr: attr, Func: "le", Args: []string{v}}
+}
+
+// Between returns filter which matches attributes between lo and hi, inclusive.
+func Between(attr, lo, hi string) Filter {
+	return Filter{Attr: attr, Func: "between", Args: []string{lo, hi}}
+}
+
+// FindOptions configure Find.
+type FindOptions struct {
+	// Filters are attribute filters
+	Filters []Filter
+	// OrderBy is the name of attribute to order the results by
+	OrderBy string
+	// Desc orders the results in descending order
+	Desc bool
+	// First limits the number of queried attribute nodes, see WithFirst
+	First int
+}
+
+// FindOption is Find option.
+type FindOption func(*FindOptions)
+
+// WithFilters configures Find attribute filters.
+func WithFilters(f ...Filter) FindOption {
+	return func(o *FindOptions) {
+		o.Filters = append(o.Filters, f...)
+	}
+}
+
+// WithOrder configures Find results ordering.
+func WithOrder(attr string, desc bool) FindOption {
+	return func(o *FindOptions) {
+		o.OrderBy = attr
+		o.Desc = desc
+	}
+}
+
+// WithFirst limits the number of Find results.
+// NOTE: the limit applies to the matching attribute nodes before the nodes
+// which do not belong to any entity, e.g. the attributes of resources, are dropped,
+// so Find may return fewer than n entities even if there are more matching ones.
+// Use a larger n, or narrow the filters, if exactly n entities are needed.
+func WithFirst(n int) FindOption {
+	return func(o *FindOptions) {
+		o.First = n
+	}
+}
+
+// Find returns entities whose typed attributes match all the given filters.
+// Only attributes declared by WithAttrTypes can be used in filters and ordering.
+// It returns ErrUnknownAttr if any of the queried attributes is not typed
+// and ErrInvalidQuery if neither filters nor ordering are given.
+// Find returns at most n entities if WithFirst(n) is given, possibly fewer; see WithFirst.
+func (s *Store) Find(ctx context.Context, opts ...FindOption) ([]store.Entity, error) {
+	fopts := FindOptions{}
+	for _, apply := range opts {
+		apply(&fopts)
+	}
+
+	preds, err := s.attrPredicates(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := s.findRequest(ctx, fopts, preds)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.do(ctx, QueryOp, req)
+	if err != nil {
+		return nil, fmt.Errorf("txn.Find: %w", err)
+	}
+
+	return decodeJSONFind(resp.Json)
+}
+
+// findRequest creates a dgraph API request for finding entities and returns it.
+// Entity attributes are queried with the given predicates.
+// NOTE: the query starts from the indexed attribute nodes and
+// traverses the reverse attrs edges to the entities. The first limit is applied
+// to the attribute nodes before @cascade drops the ones with no entity.
+func (s *Store) findRequest(ctx context.Context, opts FindOptions, preds []string) (*dgapi.Request, error) {
+	attrs := make([]string, 0, len(opts.Filters)+1)
+	for _, f := range opts.Filters {
+		attrs = append(attrs, f.Attr)
+	}
+
+	if opts.OrderBy != "" {
+		attrs = append(attrs, opts.OrderBy)
+	}
+
+	if len(attrs) == 0 {
+		return nil, ErrInvalidQuery
+	}
+
+	for _, a := range attrs {
+		if _, ok := s.opts.AttrTypes[a]; !ok {
+			return nil, fmt.Errorf("find attribute %q: %w", a, ErrUnknownAttr)
+		}
+	}
+
+	root := "has(" + opts.OrderBy + ")"
+	filters := opts.Filters
+	if len(filters) > 0 {
+		root = filters[0].dql()
+		filters = filters[1:]
+	}
+
+	args := []string{"func: " + root}
+
+	if opts.OrderBy != "" {
+		order := "orderasc"
+		if opts.Desc {
+			order = "orderdesc"
+		}
+		args = append(args, order+": "+opts.OrderBy)
+	}
+
+	if opts.First > 0 {
+		args = append(args, "first: "+strconv.Itoa(opts.First))
+	}
+
+	filter := ""
+	if len(filters) > 0 {
+		fx := make([]string, len(filters))
+		for i, f := range filters {
+			fx[i] = f.dql()
+		}
+		filter = " @filter(" + strings.Join(fx, " AND ") + ")"
+	}
+
+	q := `
+	{
+		find(` + strings.Join(args, ", ") + `)` + filter + ` @cascade {
+			~attrs @filter(type(Entity)) {
+				xid
+				type
+				name
+				namespace
+				resource {
+					xid
+					type
+					name
+					group
+					version
+					kind
+					namespaced
+				}` + attrsSelection(preds) + `
+			}
+		}
+	}
+	`
+
+	return &dgapi.Request{
+		Query:    q,
+		ReadOnly: true,
+	}, nil
+}
+
+// decodeJSONFind decodes find query JSON response and returns the found entities.
+func decodeJSONFind(b []byte) ([]store.Entity, error) {
+	var result struct {
+		Find []struct {
+			Entities []*Entity `json:"~attrs"`
+		} `json:"find"`
+	}
+
+	if err := json.Unmarshal(b, &result); err != nil {
+		return nil, fmt.Errorf("decodeJSONFind: %w", err)
+	}
+
+	// nolint:prealloc
+	var ents []store.Entity
+
+	for _, f := range result.Find {
+		for _, e := range f.Entities {
+			ent, err := entityToSpaceEntity(e)
+			if err != nil {
+				return nil, err
+			}
+			ents = append(ents, ent)
+		}
+	}
+
+	return ents, nil
+}
@@ -3,6 +3,8 @@ package dgraph
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
+	"time"
 
 	dgapi "github.com/dgraph-io/dgo/v200/protos/api"
 	"github.com/milosgajdos/netscrape/pkg/attrs"
@@ -51,6 +53,44 @@ func upsertReqJSON(op Op, e interface{}, query, cond string) (*dgapi.Request, er
 	}, nil
 }
 
+// refVar returns the name of query variable referenced by dgraph uid reference ref.
+// It returns empty string if ref does not reference a query variable.
+func refVar(ref string) string {
+	if !strings.HasPrefix(ref, "uid(") || !strings.HasSuffix(ref, ")") {
+		return ""
+	}
+
+	return ref[len("uid(") : len(ref)-1]
+}
+
+// createdAtMutations returns mutations which set created_at of the nodes referenced by refs.
+// created_at is set only when the referenced node is created, i.e. when its query variable is empty.
+// Nodes referenced by dgraph uids already exist so no mutation is returned for them.
+func createdAtMutations(refs ...string) ([]*dgapi.Mutation, error) {
+	now := time.Now().UTC().Format(time.RFC3339)
+
+	// nolint:prealloc
+	var mutations []*dgapi.Mutation
+
+	for _, ref := range refs {
+		v := refVar(ref)
+		if v == "" {
+			continue
+		}
+
+		node := map[string]string{"uid": ref, "created_at": now}
+
+		mu, err := MutationJSON(AddOp, node, `@if(eq(len(`+v+`), 0))`)
+		if err != nil {
+			return nil, err
+		}
+
+		mutations = append(mutations, mu)
+	}
+
+	return mutations, nil
+}
+
 // MutationJSON returns JSON mutation for the given op with the given cond.
 func MutationJSON(op Op, e interface{}, cond string) (*dgapi.Mutation, error) {
 	pb, err := json.Marshal(e)
@@ -84,7 +124,7 @@ func resourceToSpaceResource(r *Resource) (space.Resource, error) {
 		return nil, err
 	}
 
-	a, err := attrs.NewFromMap(r.Attrs)
+	a, err := attrs.NewFromMap(stringAttrs(r.Attrs))
 	if err != nil {
 		return nil, err
 	}
@@ -108,7 +148,7 @@ func entityToSpaceEntity(o *Entity) (space.Entity, error) {
 		return nil, err
 	}
 
-	a, err := attrs.NewFromMap(o.Attrs)
+	a, err := attrs.NewFromMap(stringAttrs(o.Attrs))
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,385 @@
+package dgraph
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	dgapi "github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/milosgajdos/netscrape/pkg/entity"
+	"github.com/milosgajdos/netscrape/pkg/store"
+)
+
+const (
+	// OrderByName orders listed entities by name.
+	OrderByName = "name"
+	// OrderByCreatedAt orders listed entities by creation time.
+	OrderByCreatedAt = "created_at"
+)
+
+// ListOptions configure List.
+type ListOptions struct {
+	// Type is the type of listed entities
+	Type entity.Type
+	// Kind filters entities by resource kind
+	Kind string
+	// Group filters entities by resource group
+	Group string
+	// Version filters entities by resource version
+	Version string
+	// Namespace filters entities by namespace
+	Namespace string
+	// OrderBy is either OrderByName or OrderByCreatedAt.
+	// Entities are listed in dgraph uid order if it is empty.
+	OrderBy string
+	// Desc orders the results in descending order
+	Desc bool
+	// Limit is the maximum number of listed entities
+	Limit int
+	// Cursor is the cursor returned by the previous List call
+	Cursor string
+}
+
+// ListOption is List option.
+type ListOption func(*ListOptions)
+
+// WithType configures the type of listed entities.
+func WithType(t entity.Type) ListOption {
+	return func(o *ListOptions) {
+		o.Type = t
+	}
+}
+
+// WithKind filters listed entities by resource kind.
+func WithKind(k string) ListOption {
+	return func(o *ListOptions) {
+		o.Kind = k
+	}
+}
+
+// WithGroup filters listed entities by resource group.
+func WithGroup(g string) ListOption {
+	return func(o *ListOptions) {
+		o.Group = g
+	}
+}
+
+// WithVersion filters listed entities by resource version.
+func WithVersion(v string) ListOption {
+	return func(o *ListOptions) {
+		o.Version = v
+	}
+}
+
+// WithNamespace filters listed entities by namespace.
+func WithNamespace(ns string) ListOption {
+	return func(o *ListOptions) {
+		o.Namespace = ns
+	}
+}
+
+// WithSort configures List results ordering.
+func WithSort(orderBy string, desc bool) ListOption {
+	return func(o *ListOptions) {
+		o.OrderBy = orderBy
+		o.Desc = desc
+	}
+}
+
+// WithLimit limits the number of List results.
+func WithLimit(n int) ListOption {
+	return func(o *ListOptions) {
+		o.Limit = n
+	}
+}
+
+// WithCursor configures List to continue after the given cursor.
+func WithCursor(c string) ListOption {
+	return func(o *ListOptions) {
+		o.Cursor = c
+	}
+}
+
+// ListResult is a page of listed entities.
+type ListResult struct {
+	// Entities are listed entities
+	Entities []store.Entity
+	// Cursor points to the next page.
+	// It is empty if there are no more entities to list.
+	Cursor string
+}
+
+// cursor is List pagination cursor.
+// Entities listed in uid order are paginated with dgraph after.
+// Ordered entities are paginated with keyset of the last listed entity:
+// the next page lists the entities with the same sort value listed after its uid
+// followed by the entities whose sort value follows its sort value.
+// NOTE: dgraph lists entities with the same sort value in uid order.
+type cursor struct {
+	After string `json:"after,omitempty"`
+	Value string `json:"value,omitempty"`
+}
+
+// encode returns opaque string representation of c.
+func (c cursor) encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeCursor decodes cursor from its string representation.
+// It returns ErrInvalidQuery if s is not a valid cursor.
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+
+	if s == "" {
+		return c, nil
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, fmt.Errorf("cursor %q: %w", s, ErrInvalidQuery)
+	}
+
+	if err := json.Unmarshal(b, &c); err != nil {
+		return c, fmt.Errorf("cursor %q: %w", s, ErrInvalidQuery)
+	}
+
+	if strings.ContainsAny(c.After, " ,()\"") {
+		return c, fmt.Errorf("cursor %q: %w", s, ErrInvalidQuery)
+	}
+
+	return c, nil
+}
+
+// List returns a page of entities of the configured type.
+// Entities are listed by default; resources are listed with WithType(entity.ResourceType).
+// The returned cursor can be passed to WithCursor to list the next page.
+// It returns ErrInvalidQuery if the options or the cursor are invalid.
+func (s *Store) List(ctx context.Context, opts ...ListOption) (*ListResult, error) {
+	lopts := ListOptions{
+		Type:  entity.EntityType,
+		Limit: DefaultPageSize,
+	}
+
+	for _, apply := range opts {
+		apply(&lopts)
+	}
+
+	c, err := decodeCursor(lopts.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	preds, err := s.attrPredicates(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := s.listRequest(ctx, lopts, c, preds)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.do(ctx, QueryOp, req)
+	if err != nil {
+		return nil, fmt.Errorf("txn.List: %w", err)
+	}
+
+	return decodeJSONList(resp.Json, lopts)
+}
+
+// listRequest creates a dgraph API request for listing entities and returns it.
+// Entity attributes are queried with the given predicates.
+// NOTE: entities filtered by resource fields are collected
+// by traversing the reverse resource edges of matching resources.
+func (s *Store) listRequest(ctx context.Context, opts ListOptions, c cursor, preds []string) (*dgapi.Request, error) {
+	if opts.Limit <= 0 {
+		return nil, fmt.Errorf("list limit %d: %w", opts.Limit, ErrInvalidQuery)
+	}
+
+	switch opts.OrderBy {
+	case "", OrderByName, OrderByCreatedAt:
+	default:
+		return nil, fmt.Errorf("list order %q: %w", opts.OrderBy, ErrInvalidQuery)
+	}
+
+	if opts.OrderBy == "" && (opts.Desc || c.Value != "") {
+		return nil, fmt.Errorf("list uid order: %w", ErrInvalidQuery)
+	}
+
+	var resFilters []string
+	for _, f := range []struct{ pred, val string }{
+		{"kind", opts.Kind},
+		{"group", opts.Group},
+		{"version", opts.Version},
+	} {
+		if f.val != "" {
+			resFilters = append(resFilters, "eq("+f.pred+", "+strconv.Quote(f.val)+")")
+		}
+	}
+
+	var (
+		block   string
+		root    string
+		filters []string
+		fields  string
+	)
+
+	switch opts.Type {
+	case entity.ResourceType:
+		if opts.Namespace != "" {
+			return nil, fmt.Errorf("list resource namespace: %w", ErrInvalidQuery)
+		}
+		root = "type(Resource)"
+		filters = resFilters
+		fields = "xid type name group version kind namespaced"
+	case entity.EntityType:
+		root = "type(Entity)"
+		if len(resFilters) > 0 {
+			block = `
+		var(func: type(Resource)) @filter(` + strings.Join(resFilters, " AND ") + `) {
+			l as ~resource
+		}
+		`
+			root = "uid(l)"
+		}
+		if opts.Namespace != "" {
+			filters = append(filters, "eq(namespace, "+strconv.Quote(opts.Namespace)+")")
+		}
+		filters = append(filters, "type(Entity)")
+		fields = `xid type name namespace
+				resource {
+					xid
+					type
+					name
+					group
+					version
+					kind
+					namespaced` + attrsSelection(preds) + `
+				}`
+	default:
+		return nil, fmt.Errorf("list type %v: %w", opts.Type, ErrInvalidQuery)
+	}
+
+	if opts.OrderBy != "" {
+		fields += `
+				sortval: ` + opts.OrderBy
+	}
+
+	fields += attrsSelection(preds)
+
+	query := func(name string, args, filters []string) string {
+		args = append([]string{"func: " + root, "first: " + strconv.Itoa(opts.Limit)}, args...)
+
+		filter := ""
+		if len(filters) > 0 {
+			filter = " @filter(" + strings.Join(filters, " AND ") + ")"
+		}
+
+		return `
+		` + name + `(` + strings.Join(args, ", ") + `)` + filter + ` {
+			uid
+			` + fields + `
+		}
+		`
+	}
+
+	var q string
+
+	switch {
+	case opts.OrderBy == "" && c.After == "":
+		q = query("list", nil, filters)
+	case opts.OrderBy == "":
+		q = query("list", []string{"after: " + c.After}, filters)
+	default:
+		order, cmp := "orderasc", "gt"
+		if opts.Desc {
+			order, cmp = "orderdesc", "lt"
+		}
+
+		args := []string{order + ": " + opts.OrderBy}
+
+		if c.After == "" {
+			q = query("list", args, filters)
+			break
+		}
+
+		val := strconv.Quote(c.Value)
+
+		eq := append(append([]string{}, filters...), "eq("+opts.OrderBy+", "+val+")")
+		next := append(append([]string{}, filters...), cmp+"("+opts.OrderBy+", "+val+")")
+
+		q = query("ties", []string{"after: " + c.After}, eq) + query("list", args, next)
+	}
+
+	return &dgapi.Request{
+		Query:    `{` + block + q + `}`,
+		ReadOnly: true,
+	}, nil
+}
+
+// listNode is listed dgraph node.
+type listNode struct {
+	node
+	// SortVal is the value listed nodes are ordered by
+	SortVal string `json:"sortval,omitempty"`
+}
+
+// decodeJSONList decodes list query JSON response and returns the listed page.
+// The entities with the same sort value as the previous page cursor are listed first.
+func decodeJSONList(b []byte, opts ListOptions) (*ListResult, error) {
+	var result struct {
+		Ties []*listNode `json:"ties"`
+		List []*listNode `json:"list"`
+	}
+
+	if err := json.Unmarshal(b, &result); err != nil {
+		return nil, fmt.Errorf("decodeJSONList: %w", err)
+	}
+
+	list := append(result.Ties, result.List...)
+	if len(list) > opts.Limit {
+		list = list[:opts.Limit]
+	}
+
+	res := &ListResult{
+		Entities: make([]store.Entity, 0, len(list)),
+	}
+
+	for _, n := range list {
+		var (
+			ent store.Entity
+			err error
+		)
+
+		if opts.Type == entity.ResourceType {
+			ent, err = resourceToSpaceResource(n.resource())
+		} else {
+			ent, err = entityToSpaceEntity(&n.Entity)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		res.Entities = append(res.Entities, ent)
+	}
+
+	if len(list) < opts.Limit {
+		return res, nil
+	}
+
+	last := list[len(list)-1]
+
+	next := cursor{After: last.UID}
+	if opts.OrderBy != "" {
+		next.Value = last.SortVal
+	}
+
+	res.Cursor = next.encode()
+
+	return res, nil
+}
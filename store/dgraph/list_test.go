@@ -0,0 +1,172 @@
+package dgraph
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/milosgajdos/netscrape/pkg/entity"
+)
+
+func TestCursor(t *testing.T) {
+	for _, c := range []cursor{{After: "0x2a"}, {After: "0x2a", Value: "ent1"}} {
+		got, err := decodeCursor(c.encode())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got != c {
+			t.Errorf("expected cursor: %#v, got: %#v", c, got)
+		}
+	}
+
+	for _, s := range []string{"!", "bm90LWpzb24", (cursor{After: "0x1) {"}).encode()} {
+		if _, err := decodeCursor(s); !errors.Is(err, ErrInvalidQuery) {
+			t.Errorf("cursor %q: got: %v, want: %v", s, err, ErrInvalidQuery)
+		}
+	}
+}
+
+func TestListRequest(t *testing.T) {
+	s := &Store{}
+
+	opts := ListOptions{
+		Type:      entity.EntityType,
+		Kind:      "repo",
+		Namespace: "entNs",
+		OrderBy:   OrderByName,
+		Limit:     10,
+	}
+
+	req, err := s.listRequest(context.Background(), opts, cursor{After: "0x2a", Value: "ent1"}, []string{"stars"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		`var(func: type(Resource)) @filter(eq(kind, "repo"))`,
+		`ties(func: uid(l), first: 10, after: 0x2a) @filter(eq(namespace, "entNs") AND type(Entity) AND eq(name, "ent1"))`,
+		`list(func: uid(l), first: 10, orderasc: name) @filter(eq(namespace, "entNs") AND type(Entity) AND gt(name, "ent1"))`,
+		`sortval: name`,
+		`attrs { stars }`,
+	} {
+		if !strings.Contains(req.Query, want) {
+			t.Errorf("expected query to contain: %s, got: %s", want, req.Query)
+		}
+	}
+
+	opts = ListOptions{Type: entity.ResourceType, Group: "core", Limit: 5}
+
+	req, err = s.listRequest(context.Background(), opts, cursor{After: "0x2a"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := `list(func: type(Resource), first: 5, after: 0x2a) @filter(eq(group, "core"))`; !strings.Contains(req.Query, want) {
+		t.Errorf("expected query to contain: %s, got: %s", want, req.Query)
+	}
+
+	testCases := []struct {
+		name string
+		opts ListOptions
+		c    cursor
+	}{
+		{"Limit", ListOptions{Type: entity.EntityType}, cursor{}},
+		{"Order", ListOptions{Type: entity.EntityType, OrderBy: "foo", Limit: 1}, cursor{}},
+		{"UIDDesc", ListOptions{Type: entity.EntityType, Desc: true, Limit: 1}, cursor{}},
+		{"UIDValue", ListOptions{Type: entity.EntityType, Limit: 1}, cursor{After: "0x1", Value: "ent1"}},
+		{"ResourceNamespace", ListOptions{Type: entity.ResourceType, Namespace: "entNs", Limit: 1}, cursor{}},
+		{"Type", ListOptions{Type: entity.UnknownType, Limit: 1}, cursor{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := s.listRequest(context.Background(), tc.opts, tc.c, nil); !errors.Is(err, ErrInvalidQuery) {
+				t.Errorf("got: %v, want: %v", err, ErrInvalidQuery)
+			}
+		})
+	}
+}
+
+func TestDecodeJSONList(t *testing.T) {
+	data := []byte(`{
+		"list": [{
+			"uid": "0x1",
+			"xid": "ent1/entNs",
+			"type": "Entity",
+			"name": "ent1",
+			"namespace": "entNs",
+			"resource": {"xid": "nodeResUID", "name": "nodeResName"}
+		}, {
+			"uid": "0x2",
+			"xid": "ent2/entNs",
+			"type": "Entity",
+			"name": "ent2",
+			"namespace": "entNs",
+			"resource": {"xid": "nodeResUID", "name": "nodeResName"}
+		}]
+	}`)
+
+	opts := ListOptions{Type: entity.EntityType, Limit: 2}
+
+	res, err := decodeJSONList(data, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(res.Entities) != 2 {
+		t.Fatalf("expected entities: %d, got: %d", 2, len(res.Entities))
+	}
+
+	if want := (cursor{After: "0x2"}).encode(); res.Cursor != want {
+		t.Errorf("expected cursor: %s, got: %s", want, res.Cursor)
+	}
+
+	opts.Limit = 3
+
+	res, err = decodeJSONList(data, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Cursor != "" {
+		t.Errorf("expected empty cursor, got: %s", res.Cursor)
+	}
+
+	data = []byte(`{
+		"ties": [{"uid": "0x3", "xid": "ent3/entNs", "name": "ent1", "sortval": "ent1", "resource": {"xid": "nodeResUID", "name": "nodeResName"}}],
+		"list": [{"uid": "0x1", "xid": "ent2/entNs", "name": "ent2", "sortval": "ent2", "resource": {"xid": "nodeResUID", "name": "nodeResName"}}]
+	}`)
+
+	opts = ListOptions{Type: entity.EntityType, OrderBy: OrderByName, Limit: 1}
+
+	res, err = decodeJSONList(data, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// NOTE: the entities with the same sort value as the cursor are listed first
+	if len(res.Entities) != 1 || res.Entities[0].UID().Value() != "ent3/entNs" {
+		t.Fatalf("expected tied entity, got: %v", res.Entities)
+	}
+
+	if want := (cursor{After: "0x3", Value: "ent1"}).encode(); res.Cursor != want {
+		t.Errorf("expected cursor: %s, got: %s", want, res.Cursor)
+	}
+
+	data = []byte(`{"list": [{"uid": "0x3", "xid": "resUID", "name": "repo", "group": "core", "kind": "repo"}]}`)
+
+	res, err = decodeJSONList(data, ListOptions{Type: entity.ResourceType, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(res.Entities) != 1 {
+		t.Fatalf("expected entities: %d, got: %d", 1, len(res.Entities))
+	}
+
+	if typ := res.Entities[0].Type(); typ != entity.ResourceType {
+		t.Errorf("expected type: %v, got: %v", entity.ResourceType, typ)
+	}
+}
@@ -14,6 +14,12 @@ const (
 	LinkOp
 	// UnlinkOp is unlink operation
 	UnlinkOp
+	// UpdateOp is update operation
+	UpdateOp
+	// QueryOp is query operation
+	QueryOp
+	// BatchOp is batch of mutation operations
+	BatchOp
 	// UnknownOp is unknown operation
 	UnknownOp
 )
@@ -31,7 +37,25 @@ func (op Op) String() string {
 		return "LinkOp"
 	case UnlinkOp:
 		return "UnlinkOp"
+	case UpdateOp:
+		return "UpdateOp"
+	case QueryOp:
+		return "QueryOp"
+	case BatchOp:
+		return "BatchOp"
 	default:
 		return "UnknownOp"
 	}
 }
+
+// OpFromString returns Op from its string representation.
+// It returns UnknownOp if s is not a valid Op.
+func OpFromString(s string) Op {
+	for op := AddOp; op < UnknownOp; op++ {
+		if op.String() == s {
+			return op
+		}
+	}
+
+	return UnknownOp
+}
@@ -1,6 +1,11 @@
 package dgraph
 
 import (
+	"strconv"
+	"time"
+
+	"github.com/milosgajdos/netscrape/pkg/attrs"
+	"github.com/milosgajdos/netscrape/pkg/store"
 	"github.com/milosgajdos/netscrape/pkg/uuid"
 	"google.golang.org/grpc"
 )
@@ -10,11 +15,33 @@ const (
 	DefaultURL = "localhost:9080"
 )
 
+const (
+	// bestEffortOpt is best-effort read operation option attribute
+	bestEffortOpt = "dgraph.op.best_effort"
+	// timeoutOpt is operation timeout option attribute
+	timeoutOpt = "dgraph.op.timeout"
+	// readTsOpt is read start timestamp operation option attribute
+	readTsOpt = "dgraph.op.read_ts"
+)
+
 // Options configure dgraph.
 type Options struct {
 	UID      uuid.UID
 	DialOpts []grpc.DialOption
 	Auth     *Auth
+	// UIDCacheSize is the size of xid to dgraph uid cache.
+	// The cache is disabled if the size is not positive.
+	UIDCacheSize int
+	// DryRun disables executing mutations.
+	DryRun bool
+	// Recorder records all dgraph API requests.
+	Recorder Recorder
+	// BestEffort enables best-effort reads.
+	BestEffort bool
+	// Timeout is a timeout of every store operation.
+	Timeout time.Duration
+	// AttrTypes declares attribute types.
+	AttrTypes map[string]AttrType
 }
 
 // Option is dgraph option
@@ -40,3 +67,129 @@ func WithAuth(a *Auth) Option {
 		o.Auth = a
 	}
 }
+
+// WithUIDCache configures the size of xid to dgraph uid LRU cache.
+func WithUIDCache(size int) Option {
+	return func(o *Options) {
+		o.UIDCacheSize = size
+	}
+}
+
+// WithDryRun configures dry-run mode.
+// In dry-run mode mutations are recorded but not executed.
+// If no Recorder is configured, the requests are logged by LogRecorder.
+func WithDryRun(d bool) Option {
+	return func(o *Options) {
+		o.DryRun = d
+	}
+}
+
+// WithRecorder configures dgraph API requests Recorder.
+func WithRecorder(r Recorder) Option {
+	return func(o *Options) {
+		o.Recorder = r
+	}
+}
+
+// WithBestEffort configures best-effort reads.
+// Best-effort reads do not need to get a timestamp from dgraph Zero,
+// so they are faster but they may not see the most recent writes.
+func WithBestEffort(b bool) Option {
+	return func(o *Options) {
+		o.BestEffort = b
+	}
+}
+
+// WithTimeout configures timeout of every store operation.
+func WithTimeout(d time.Duration) Option {
+	return func(o *Options) {
+		o.Timeout = d
+	}
+}
+
+// WithAttrTypes declares attribute types.
+// Typed attributes are stored as typed dgraph predicates which can be
+// queried by range filters and ordered. See AttrSchema.
+func WithAttrTypes(types map[string]AttrType) Option {
+	return func(o *Options) {
+		o.AttrTypes = types
+	}
+}
+
+// NOTE: store.Options can't be extended with dgraph specific options,
+// so the operation options are passed as reserved store.Options attributes
+// which are read by the store operations and never stored.
+
+// opOptions are options of a single store operation.
+type opOptions struct {
+	// BestEffort enables best-effort read
+	BestEffort bool
+	// Timeout is operation timeout
+	Timeout time.Duration
+	// ReadTs is read start timestamp
+	ReadTs uint64
+}
+
+// newOpOptions returns opOptions configured with opts.
+// Options which are not set default to the store options o.
+func newOpOptions(o Options, opts ...store.Option) opOptions {
+	oopts := opOptions{
+		BestEffort: o.BestEffort,
+		Timeout:    o.Timeout,
+	}
+
+	a := storeOptions(opts...).Attrs
+	if a == nil {
+		return oopts
+	}
+
+	if b, err := strconv.ParseBool(a.Get(bestEffortOpt)); err == nil {
+		oopts.BestEffort = b
+	}
+
+	if d, err := time.ParseDuration(a.Get(timeoutOpt)); err == nil {
+		oopts.Timeout = d
+	}
+
+	if ts, err := strconv.ParseUint(a.Get(readTsOpt), 10, 64); err == nil {
+		oopts.ReadTs = ts
+	}
+
+	return oopts
+}
+
+// withOpAttr returns store.Option which sets operation option attribute k to v.
+// NOTE: the attributes set by store.WithAttrs are copied so they are not modified.
+func withOpAttr(k, v string) store.Option {
+	return func(o *store.Options) {
+		if o.Attrs == nil {
+			a, err := attrs.New()
+			if err != nil {
+				return
+			}
+			o.Attrs = a
+		} else {
+			o.Attrs = attrs.NewCopyFrom(o.Attrs)
+		}
+
+		o.Attrs.Set(k, v)
+	}
+}
+
+// WithReadBestEffort configures best-effort read of a single store operation.
+// It overrides the store WithBestEffort option.
+func WithReadBestEffort(b bool) store.Option {
+	return withOpAttr(bestEffortOpt, strconv.FormatBool(b))
+}
+
+// WithOpTimeout configures timeout of a single store operation.
+// It overrides the store WithTimeout option.
+func WithOpTimeout(d time.Duration) store.Option {
+	return withOpAttr(timeoutOpt, d.String())
+}
+
+// WithReadTs pins the read of a single store operation to the given start timestamp,
+// e.g. to StartTs of a snapshot shared by several queries or processes.
+func WithReadTs(ts uint64) store.Option {
+	return withOpAttr(readTsOpt, strconv.FormatUint(ts, 10))
+}
@@ -0,0 +1,186 @@
+package dgraph
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+
+	dgapi "github.com/dgraph-io/dgo/v200/protos/api"
+)
+
+// Recorder records dgraph API requests.
+type Recorder interface {
+	// Record records req of the given op.
+	Record(op Op, req *dgapi.Request) error
+}
+
+// RecorderFunc is an adapter which allows using functions as Recorder.
+type RecorderFunc func(Op, *dgapi.Request) error
+
+// Record calls f(op, req).
+func (f RecorderFunc) Record(op Op, req *dgapi.Request) error {
+	return f(op, req)
+}
+
+// Record is recorded dgraph API request.
+type Record struct {
+	Op         string            `json:"op"`
+	Query      string            `json:"query,omitempty"`
+	Vars       map[string]string `json:"vars,omitempty"`
+	Mutations  []RecordMutation  `json:"mutations,omitempty"`
+	CommitNow  bool              `json:"commit_now,omitempty"`
+	ReadOnly   bool              `json:"read_only,omitempty"`
+	BestEffort bool              `json:"best_effort,omitempty"`
+	StartTs    uint64            `json:"start_ts,omitempty"`
+}
+
+// RecordMutation is recorded dgraph JSON mutation.
+type RecordMutation struct {
+	SetJSON    json.RawMessage `json:"set_json,omitempty"`
+	DeleteJSON json.RawMessage `json:"delete_json,omitempty"`
+	Cond       string          `json:"cond,omitempty"`
+}
+
+// NewRecord creates a new Record of req with the given op and returns it.
+func NewRecord(op Op, req *dgapi.Request) *Record {
+	r := &Record{
+		Op:         op.String(),
+		Query:      req.Query,
+		Vars:       req.Vars,
+		CommitNow:  req.CommitNow,
+		ReadOnly:   req.ReadOnly,
+		BestEffort: req.BestEffort,
+		StartTs:    req.StartTs,
+	}
+
+	for _, mu := range req.Mutations {
+		r.Mutations = append(r.Mutations, RecordMutation{
+			SetJSON:    mu.SetJson,
+			DeleteJSON: mu.DeleteJson,
+			Cond:       mu.Cond,
+		})
+	}
+
+	return r
+}
+
+// Request returns dgraph API request of the record.
+func (r *Record) Request() *dgapi.Request {
+	req := &dgapi.Request{
+		Query:      r.Query,
+		Vars:       r.Vars,
+		CommitNow:  r.CommitNow,
+		ReadOnly:   r.ReadOnly,
+		BestEffort: r.BestEffort,
+		StartTs:    r.StartTs,
+	}
+
+	for _, mu := range r.Mutations {
+		req.Mutations = append(req.Mutations, &dgapi.Mutation{
+			SetJson:    mu.SetJSON,
+			DeleteJson: mu.DeleteJSON,
+			Cond:       mu.Cond,
+		})
+	}
+
+	return req
+}
+
+// JSONRecorder records requests into io.Writer as JSON lines.
+type JSONRecorder struct {
+	enc *json.Encoder
+	mu  *sync.Mutex
+}
+
+// NewJSONRecorder creates a new JSON recorder which writes records into w.
+func NewJSONRecorder(w io.Writer) *JSONRecorder {
+	return &JSONRecorder{
+		enc: json.NewEncoder(w),
+		mu:  &sync.Mutex{},
+	}
+}
+
+// Record writes req of the given op as a single line JSON record.
+func (r *JSONRecorder) Record(op Op, req *dgapi.Request) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.enc.Encode(NewRecord(op, req))
+}
+
+// LogRecorder logs requests as JSON records.
+type LogRecorder struct {
+	l *log.Logger
+}
+
+// NewLogRecorder creates a new recorder which logs records with l.
+// If l is nil, records are logged to standard error.
+func NewLogRecorder(l *log.Logger) *LogRecorder {
+	if l == nil {
+		l = log.New(os.Stderr, "", log.LstdFlags)
+	}
+
+	return &LogRecorder{
+		l: l,
+	}
+}
+
+// Record logs req of the given op as a single line JSON record.
+func (r *LogRecorder) Record(op Op, req *dgapi.Request) error {
+	b, err := json.Marshal(NewRecord(op, req))
+	if err != nil {
+		return err
+	}
+
+	r.l.Print(string(b))
+
+	return nil
+}
+
+// ReadRecords reads JSON records from r and returns them.
+// It returns error if any of the records fails to be decoded.
+func ReadRecords(r io.Reader) ([]*Record, error) {
+	var records []*Record
+
+	dec := json.NewDecoder(bufio.NewReader(r))
+
+	for {
+		rec := new(Record)
+		if err := dec.Decode(rec); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, fmt.Errorf("ReadRecords: %w", err)
+		}
+		records = append(records, rec)
+	}
+
+	return records, nil
+}
+
+// Replay executes all the requests recorded in r in the order they were recorded.
+// It returns error if the records fail to be read or if any of the requests fails.
+func (s *Store) Replay(ctx context.Context, r io.Reader) error {
+	records, err := ReadRecords(r)
+	if err != nil {
+		return err
+	}
+
+	for i, rec := range records {
+		op := OpFromString(rec.Op)
+		if op == UnknownOp {
+			return fmt.Errorf("replay record %d: %w", i, ErrUnknownOp)
+		}
+
+		if _, err := s.do(ctx, op, rec.Request()); err != nil {
+			return fmt.Errorf("txn.Replay %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,169 @@
+package dgraph
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"reflect"
+	"strings"
+	"testing"
+
+	dgapi "github.com/dgraph-io/dgo/v200/protos/api"
+	"google.golang.org/grpc"
+)
+
+func TestOpFromString(t *testing.T) {
+	for op := AddOp; op <= UnknownOp; op++ {
+		if o := OpFromString(op.String()); o != op {
+			t.Errorf("expected op: %v, got: %v", op, o)
+		}
+	}
+
+	if op := OpFromString("foo"); op != UnknownOp {
+		t.Errorf("expected op: %v, got: %v", UnknownOp, op)
+	}
+}
+
+func TestDryRunRecord(t *testing.T) {
+	var buf bytes.Buffer
+
+	s := &Store{
+		opts: Options{
+			DryRun:   true,
+			Recorder: NewJSONRecorder(&buf),
+		},
+	}
+
+	obj1, err := newTestEntity("ent1", "entNs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	obj2, err := newTestEntity("ent2", "entNs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Add(context.Background(), obj1); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Link(context.Background(), obj1.UID(), obj2.UID()); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := ReadRecords(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(records) != 2 {
+		t.Fatalf("expected records: %d, got: %d", 2, len(records))
+	}
+
+	if op := OpFromString(records[1].Op); op != LinkOp {
+		t.Errorf("expected op: %v, got: %v", LinkOp, op)
+	}
+
+	req, err := s.linkRequest(context.Background(), obj1.UID(), obj2.UID())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := records[1].Request(); !reflect.DeepEqual(got, req) {
+		t.Errorf("expected request: %v, got: %v", req, got)
+	}
+}
+
+func TestDryRunRecordUncached(t *testing.T) {
+	var buf bytes.Buffer
+
+	s := &Store{
+		opts: Options{
+			DryRun:   true,
+			Recorder: NewJSONRecorder(&buf),
+		},
+		cache: newUIDCache(10),
+	}
+
+	obj1, err := newTestEntity("ent1", "entNs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	obj2, err := newTestEntity("ent2", "entNs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.cache.Put(obj1.UID().Value(), "0x1")
+	s.cache.Put(obj2.UID().Value(), "0x2")
+
+	if err := s.Add(context.Background(), obj1); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Link(context.Background(), obj1.UID(), obj2.UID()); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := NewBuffer(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.Link(context.Background(), obj1.UID(), obj2.UID()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// NOTE: cached dgraph uids can't be replayed on another database
+	if strings.Contains(buf.String(), "0x") {
+		t.Errorf("expected records without dgraph uids, got: %s", buf.String())
+	}
+
+	records, err := ReadRecords(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(records) != 3 {
+		t.Fatalf("expected records: %d, got: %d", 3, len(records))
+	}
+}
+
+func TestLogRecorder(t *testing.T) {
+	var buf bytes.Buffer
+
+	r := NewLogRecorder(log.New(&buf, "", 0))
+
+	req := &dgapi.Request{Query: "{ q(func: has(xid)) { uid } }", ReadOnly: true}
+
+	if err := r.Record(QueryOp, req); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := ReadRecords(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(records) != 1 || !reflect.DeepEqual(records[0].Request(), req) {
+		t.Errorf("expected recorded request: %v, got: %v", req, records)
+	}
+}
+
+func TestDryRunDefaultRecorder(t *testing.T) {
+	s, err := NewStore(DefaultURL, WithDryRun(true), WithDialOpts(grpc.WithInsecure()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if _, ok := s.opts.Recorder.(*LogRecorder); !ok {
+		t.Errorf("expected dry-run LogRecorder, got: %T", s.opts.Recorder)
+	}
+}
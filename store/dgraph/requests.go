@@ -3,6 +3,7 @@ package dgraph
 import (
 	"context"
 	"strconv"
+	"strings"
 
 	dgapi "github.com/dgraph-io/dgo/v200/protos/api"
 	"github.com/milosgajdos/netscrape/pkg/attrs"
@@ -40,68 +41,60 @@ func (s *Store) addRequest(ctx context.Context, e store.Entity, opts ...store.Op
 // addResourceRequest creates a dgraph API request for adding space.Resource and returns it.
 // It returns error if r fails to be serialised as a JSON object.
 func (s *Store) addResourceRequest(ctx context.Context, r space.Resource, opts ...store.Option) (*dgapi.Request, error) {
-	query := `
-	{
-		resource(func: eq(xid, "` + r.UID().Value() + `")) {
-			r as uid
-	        }
+	res, block := s.uidVar("resource", r.UID().Value(), "r", "")
+
+	query := queryBlocks(block)
+
+	req, err := upsertReqJSON(AddOp, resourceNode(r, res, s.opts.AttrTypes), query, "")
+	if err != nil {
+		return nil, err
 	}
-	`
 
-	res := &Resource{
-		UID:        "uid(r)",
-		XID:        r.UID().Value(),
-		Type:       r.Type().String(),
-		Name:       r.Name(),
-		Group:      r.Group(),
-		Version:    r.Version(),
-		Kind:       r.Kind(),
-		Namespaced: r.Namespaced(),
-		Attrs:      AttrsToMap(r.Attrs()),
-		DType:      []string{entity.ResourceType.String()},
+	mutations, err := createdAtMutations(res)
+	if err != nil {
+		return nil, err
 	}
 
-	return upsertReqJSON(AddOp, res, query, "")
+	req.Mutations = append(req.Mutations, mutations...)
+
+	return req, nil
 }
 
 // addResourceRequest creates a dgraph API request for adding space.Entity and returns it.
 // It returns error if entity fails to be serialised into JSON.
 func (s *Store) addEntityRequest(ctx context.Context, e space.Entity, opts ...store.Option) (*dgapi.Request, error) {
-	query := `
-	{
-		entity(func: eq(xid, "` + e.UID().Value() + `")) {
-			e as uid
-		}
+	ent, eblock := s.uidVar("entity", e.UID().Value(), "e", "")
+	res, rblock := s.uidVar("resource", e.Resource().UID().Value(), "r", "")
 
-		resource(func: eq(xid, "` + e.Resource().UID().Value() + `")) {
-			r as uid
-		}
+	query := queryBlocks(eblock, rblock)
+
+	obj := entityNode(e, ent, res, s.opts.AttrTypes)
+
+	req, err := upsertReqJSON(AddOp, obj, query, "")
+	if err != nil {
+		return nil, err
 	}
-	`
 
-	obj := &Entity{
-		UID:       "uid(e)",
-		XID:       e.UID().Value(),
-		Type:      e.Type().String(),
-		Name:      e.Name(),
-		Namespace: e.Namespace(),
-		Resource: &Resource{
-			UID:        "uid(r)",
-			XID:        e.Resource().UID().Value(),
-			Type:       e.Resource().Type().String(),
-			Name:       e.Resource().Name(),
-			Group:      e.Resource().Group(),
-			Version:    e.Resource().Version(),
-			Kind:       e.Resource().Kind(),
-			Namespaced: e.Resource().Namespaced(),
-			Attrs:      AttrsToMap(e.Resource().Attrs()),
-			DType:      []string{entity.ResourceType.String()},
-		},
-		Attrs: AttrsToMap(e.Attrs()),
-		DType: []string{entity.EntityType.String()},
+	mutations, err := createdAtMutations(ent, res)
+	if err != nil {
+		return nil, err
 	}
 
-	return upsertReqJSON(AddOp, obj, query, "")
+	req.Mutations = append(req.Mutations, mutations...)
+
+	return req, nil
+}
+
+// addVars returns a map of add request query variables to the xids they resolve.
+func addVars(e store.Entity) map[string]string {
+	switch v := e.(type) {
+	case space.Entity:
+		return map[string]string{"e": v.UID().Value(), "r": v.Resource().UID().Value()}
+	case space.Resource:
+		return map[string]string{"r": v.UID().Value()}
+	default:
+		return nil
+	}
 }
 
 // getRequest creates a dgraph API request for getting entity with the given uid and returns it.
@@ -110,6 +103,7 @@ func (s *Store) getRequest(ctx context.Context, uid uuid.UID, opts ...store.Opti
 	q := `
 	{
 		entity(func: eq(xid, "` + uid.Value() + `")) {
+			uid
 			expand(_all_) {
         			expand(_all_)
 				attrs
@@ -152,6 +146,21 @@ func (s *Store) linkRequest(ctx context.Context, from, to uuid.UID, opts ...stor
 		apply(&sopts)
 	}
 
+	fromUID, fromBlock := s.uidVar("var", from.Value(), "from", "type(Entity)")
+	toUID, toBlock := s.uidVar("var", to.Value(), "to", "type(Entity)")
+
+	q := queryBlocks(fromBlock, toBlock)
+
+	link := linkNode(fromUID, toUID, sopts.Attrs)
+
+	cond := ifCond("gt(len(from), 0)", "gt(len(to), 0)")
+
+	return upsertReqJSON(LinkOp, link, q, cond)
+}
+
+// unlinkRequest creates dgraph API request to remove the link between and to entities stored in the dgraph database.
+// The link is removed only if both from and to nodes exist, are both of Entity types and there is a link between them.
+func (s *Store) unlinkRequest(ctx context.Context, from, to uuid.UID, opts ...store.Option) (*dgapi.Request, error) {
 	q := `
 	{
 		var(func: eq(xid, "` + from.Value() + `")) @filter(type(Entity)) {
@@ -164,62 +173,152 @@ func (s *Store) linkRequest(ctx context.Context, from, to uuid.UID, opts ...stor
 	}
 	`
 
+	sopts := store.Options{}
+	for _, apply := range opts {
+		apply(&sopts)
+	}
+
+	link := &Entity{
+		UID: "uid(from)",
+		Links: []Entity{
+			{UID: "uid(to)"},
+		},
+	}
+
+	cond := `@if(gt(len(from), 0) AND gt(len(to), 0))`
+
+	return upsertReqJSON(UnlinkOp, link, q, cond)
+}
+
+// resourceNode returns dgraph Resource node for r with the given dgraph uid.
+// Resource attributes are encoded with the given attribute types.
+func resourceNode(r space.Resource, uid string, types map[string]AttrType) *Resource {
+	return &Resource{
+		UID:        uid,
+		XID:        r.UID().Value(),
+		Type:       r.Type().String(),
+		Name:       r.Name(),
+		Group:      r.Group(),
+		Version:    r.Version(),
+		Kind:       r.Kind(),
+		Namespaced: r.Namespaced(),
+		Attrs:      typedAttrs(r.Attrs(), types),
+		DType:      []string{entity.ResourceType.String()},
+	}
+}
+
+// entityNode returns dgraph Entity node for e with the given dgraph uid.
+// The entity resource node is assigned resUID dgraph uid.
+// Entity attributes are encoded with the given attribute types.
+func entityNode(e space.Entity, uid, resUID string, types map[string]AttrType) *Entity {
+	return &Entity{
+		UID:       uid,
+		XID:       e.UID().Value(),
+		Type:      e.Type().String(),
+		Name:      e.Name(),
+		Namespace: e.Namespace(),
+		Resource:  resourceNode(e.Resource(), resUID, types),
+		Attrs:     typedAttrs(e.Attrs(), types),
+		DType:     []string{entity.EntityType.String()},
+	}
+}
+
+// linkNode returns dgraph Entity node which links from and to dgraph uids.
+// Link relation and weight facets are read from a; defaults are used if they are not set.
+func linkNode(from, to string, a attrs.Attrs) *Entity {
 	weight := DefaultWeight
 	relation := DefaultRelation
 
-	if sopts.Attrs != nil {
-		if w, err := strconv.ParseFloat(sopts.Attrs.Get(attrs.Weight), 64); err == nil {
+	if a != nil {
+		if w, err := strconv.ParseFloat(a.Get(attrs.Weight), 64); err == nil {
 			if w != 0.0 {
 				weight = w
 			}
 		}
 
-		if r := sopts.Attrs.Get(attrs.Relation); r != "" {
+		if r := a.Get(attrs.Relation); r != "" {
 			relation = r
 		}
 	}
 
-	link := &Entity{
-		UID:   "uid(from)",
+	return &Entity{
+		UID:   from,
 		DType: []string{entity.EntityType.String()},
 		Links: []Entity{
-			{UID: "uid(to)", DType: []string{entity.EntityType.String()}, Relation: relation, Weight: weight},
+			{UID: to, DType: []string{entity.EntityType.String()}, Relation: relation, Weight: weight},
 		},
 	}
+}
 
-	cond := `@if(gt(len(from), 0) AND gt(len(to), 0))`
+// uidVar returns dgraph uid reference uid(v) of the node with the given xid
+// and a query block which resolves the node into variable v if it satisfies cond.
+func (s *Store) uidVar(name, xid, v, cond string) (string, string) {
+	block := `
+		` + name + `(func: ` + xidFunc(s.cache, xid, cond) + ` {
+			xid
+			` + v + ` as uid
+		}
+	`
 
-	return upsertReqJSON(LinkOp, link, q, cond)
+	return "uid(" + v + ")", block
 }
 
-// unlinkRequest creates dgraph API request to remove the link between and to entities stored in the dgraph database.
-// The link is removed only if both from and to nodes exist, are both of Entity types and there is a link between them.
-func (s *Store) unlinkRequest(ctx context.Context, from, to uuid.UID, opts ...store.Option) (*dgapi.Request, error) {
-	q := `
-	{
-		var(func: eq(xid, "` + from.Value() + `")) @filter(type(Entity)) {
-			from as uid
-		}
+// xidFunc returns dgraph query root function and filter which find the node
+// with the given xid if it satisfies cond, e.g. eq(xid, "foo")) @filter(type(Entity)).
+// If the dgraph uid of xid is cached the node is looked up by its uid
+// and filtered by its xid, so nodes deleted since they were cached are not found.
+func xidFunc(cache *uidCache, xid, cond string) string {
+	var conds []string
 
-		var(func: eq(xid, "` + to.Value() + `")) @filter(type(Entity)) {
-			to as uid
-		}
+	root := `eq(xid, "` + xid + `")`
+
+	if uid, ok := cache.Get(xid); ok {
+		conds = append(conds, root)
+		root = "uid(" + uid + ")"
 	}
-	`
 
-	sopts := store.Options{}
-	for _, apply := range opts {
-		apply(&sopts)
+	if cond != "" {
+		conds = append(conds, cond)
 	}
 
-	link := &Entity{
-		UID: "uid(from)",
-		Links: []Entity{
-			{UID: "uid(to)"},
-		},
+	if len(conds) == 0 {
+		return root + ")"
 	}
 
-	cond := `@if(gt(len(from), 0) AND gt(len(to), 0))`
+	return root + ") @filter(" + strings.Join(parens(conds...), " AND ") + ")"
+}
 
-	return upsertReqJSON(UnlinkOp, link, q, cond)
+// parens returns conds enclosed in parentheses if there are more than one.
+func parens(conds ...string) []string {
+	if len(conds) < 2 {
+		return conds
+	}
+
+	out := make([]string, len(conds))
+	for i, c := range conds {
+		out[i] = "(" + c + ")"
+	}
+
+	return out
+}
+
+// queryBlocks returns dgraph query which consists of the given query blocks.
+// It returns empty string if all the blocks are empty.
+func queryBlocks(blocks ...string) string {
+	q := strings.Join(blocks, "")
+	if q == "" {
+		return ""
+	}
+
+	return "\n\t{" + q + "}\n\t"
+}
+
+// ifCond returns dgraph mutation condition which requires all conds to be true.
+// It returns empty condition if no conds are given.
+func ifCond(conds ...string) string {
+	if len(conds) == 0 {
+		return ""
+	}
+
+	return "@if(" + strings.Join(conds, " AND ") + ")"
 }
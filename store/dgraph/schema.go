@@ -32,4 +32,21 @@ const SpaceDQLSchema = `
 	kind: string @index(exact) .
 	namespaced: bool .
 	resource: uid @count @reverse .
+	attrs: uid @reverse .
 `
+
+// spacePredicates are predicates defined in SpaceDQLSchema.
+var spacePredicates = map[string]bool{
+	"xid":        true,
+	"type":       true,
+	"name":       true,
+	"namespace":  true,
+	"links":      true,
+	"created_at": true,
+	"group":      true,
+	"version":    true,
+	"kind":       true,
+	"namespaced": true,
+	"resource":   true,
+	"attrs":      true,
+}
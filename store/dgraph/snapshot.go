@@ -0,0 +1,118 @@
+package dgraph
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	dgo "github.com/dgraph-io/dgo/v200"
+	dgapi "github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/milosgajdos/netscrape/pkg/store"
+)
+
+// snapshot is a read-only transaction pinned to a start timestamp.
+// Snapshots created at a given start timestamp have no transaction.
+type snapshot struct {
+	txn *dgo.Txn
+	// c queries snapshots with no transaction
+	c *Client
+	// ts is snapshot start timestamp
+	ts uint64
+	// mu serializes snapshot queries
+	mu *sync.Mutex
+}
+
+// do executes read-only req at the snapshot start timestamp.
+func (s *snapshot) do(ctx context.Context, req *dgapi.Request) (*dgapi.Response, error) {
+	if s.txn == nil {
+		req.StartTs = s.ts
+		return s.c.queryAt(ctx, req)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.txn.Do(ctx, req)
+}
+
+// close discards the snapshot transaction.
+func (s *snapshot) close() error {
+	if s.txn == nil {
+		return nil
+	}
+
+	return s.txn.Discard(context.Background())
+}
+
+// Snapshot returns a read-only view of the store pinned to the current start timestamp.
+// All the queries of the returned store see the same consistent state of the database
+// regardless of the mutations committed after the snapshot was created.
+// The returned store must be closed when no longer needed; closing it does not close
+// the connection of the original store. Mutating the snapshot returns ErrSnapshotReadOnly.
+func (s *Store) Snapshot(ctx context.Context) (*Store, error) {
+	txn := s.c.NewReadOnlyTxn()
+
+	// NOTE: dgraph assigns start timestamp to transaction on its first query
+	req := &dgapi.Request{
+		Query: `
+		{
+			snapshot(func: has(xid), first: 1) {
+				uid
+			}
+		}
+		`,
+		ReadOnly: true,
+	}
+
+	resp, err := txn.Do(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("txn.Snapshot: %w", err)
+	}
+
+	// NOTE: stale uids read at the snapshot must not be cached
+	return &Store{
+		c:    s.c,
+		opts: s.opts,
+		snap: &snapshot{
+			txn: txn,
+			ts:  resp.GetTxn().GetStartTs(),
+			mu:  &sync.Mutex{},
+		},
+	}, nil
+}
+
+// SnapshotAt returns a read-only view of the store pinned to the start timestamp ts,
+// e.g. to StartTs of a snapshot created by another process.
+// Unlike Snapshot it does not query dgraph, so ts is not validated until the first query.
+// It returns store.ErrUnsupported if the store is logged in with ACL credentials.
+func (s *Store) SnapshotAt(ts uint64) (*Store, error) {
+	if ts == 0 {
+		return nil, fmt.Errorf("snapshot at %d: %w", ts, ErrInvalidQuery)
+	}
+
+	if s.c != nil && s.c.auth {
+		return nil, fmt.Errorf("snapshot at %d with ACL: %w", ts, store.ErrUnsupported)
+	}
+
+	// NOTE: stale uids read at the snapshot must not be cached
+	return &Store{
+		c:    s.c,
+		opts: s.opts,
+		snap: &snapshot{
+			c:  s.c,
+			ts: ts,
+			mu: &sync.Mutex{},
+		},
+	}, nil
+}
+
+// StartTs returns the start timestamp of snapshot store.
+// It can be passed to SnapshotAt or WithReadTs to read the same state of the database.
+// It returns 0 if the store is not a snapshot.
+func (s *Store) StartTs() uint64 {
+	if s.snap == nil {
+		return 0
+	}
+
+	return s.snap.ts
+}
@@ -0,0 +1,130 @@
+package dgraph
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	dgapi "github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/milosgajdos/netscrape/pkg/attrs"
+	"github.com/milosgajdos/netscrape/pkg/store"
+)
+
+func TestSnapshotReadOnly(t *testing.T) {
+	s := &Store{snap: &snapshot{}}
+
+	obj, err := newTestEntity("ent1", "entNs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Add(context.Background(), obj); !errors.Is(err, ErrSnapshotReadOnly) {
+		t.Fatalf("got: %v, want: %v", err, ErrSnapshotReadOnly)
+	}
+}
+
+func TestBestEffort(t *testing.T) {
+	errStop := errors.New("stop")
+
+	var got *dgapi.Request
+
+	s := &Store{
+		opts: Options{
+			BestEffort: true,
+			Recorder: RecorderFunc(func(op Op, req *dgapi.Request) error {
+				got = req
+				return errStop
+			}),
+		},
+	}
+
+	obj, err := newTestEntity("ent1", "entNs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Get(context.Background(), obj.UID()); !errors.Is(err, errStop) {
+		t.Fatalf("got: %v, want: %v", err, errStop)
+	}
+
+	if got == nil || !got.BestEffort || !got.ReadOnly {
+		t.Errorf("expected best-effort read-only request, got: %v", got)
+	}
+}
+
+func TestReadOptions(t *testing.T) {
+	errStop := errors.New("stop")
+
+	var got *dgapi.Request
+
+	s := &Store{
+		opts: Options{
+			BestEffort: true,
+			Recorder: RecorderFunc(func(op Op, req *dgapi.Request) error {
+				got = req
+				return errStop
+			}),
+		},
+	}
+
+	obj, err := newTestEntity("ent1", "entNs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Get(context.Background(), obj.UID(), WithReadBestEffort(false), WithReadTs(10)); !errors.Is(err, errStop) {
+		t.Fatalf("got: %v, want: %v", err, errStop)
+	}
+
+	if got == nil || got.BestEffort || got.StartTs != 10 {
+		t.Errorf("expected request at start ts %d without best-effort, got: %v", 10, got)
+	}
+
+	a, err := attrs.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Get(context.Background(), obj.UID(), store.WithAttrs(a), WithReadTs(10)); !errors.Is(err, errStop) {
+		t.Fatalf("got: %v, want: %v", err, errStop)
+	}
+
+	if got == nil || got.StartTs != 10 {
+		t.Errorf("expected request at start ts %d, got: %v", 10, got)
+	}
+
+	if v := a.Get(readTsOpt); v != "" {
+		t.Errorf("unexpected operation option in caller attributes: %s", v)
+	}
+}
+
+func TestSnapshotAt(t *testing.T) {
+	s := &Store{c: &Client{}, cache: newUIDCache(10)}
+
+	if _, err := s.SnapshotAt(0); !errors.Is(err, ErrInvalidQuery) {
+		t.Errorf("got: %v, want: %v", err, ErrInvalidQuery)
+	}
+
+	snap, err := s.SnapshotAt(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ts := snap.StartTs(); ts != 10 {
+		t.Errorf("expected start ts: %d, got: %d", 10, ts)
+	}
+
+	if snap.cache != nil {
+		t.Errorf("expected snapshot with no uid cache")
+	}
+
+	if err := snap.Close(); err != nil {
+		t.Errorf("failed to close snapshot: %v", err)
+	}
+
+	s.c.auth = true
+
+	if _, err := s.SnapshotAt(10); !errors.Is(err, store.ErrUnsupported) {
+		t.Errorf("got: %v, want: %v", err, store.ErrUnsupported)
+	}
+}
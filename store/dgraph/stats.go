@@ -0,0 +1,435 @@
+package dgraph
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	dgapi "github.com/dgraph-io/dgo/v200/protos/api"
+)
+
+const (
+	// DefaultTopN is default number of top entities returned in Stats.
+	DefaultTopN = 10
+)
+
+// DefaultRelations returns the relations whose links are counted in Stats by default:
+// DefaultRelation and the relations of GitHub stars space.
+func DefaultRelations() []string {
+	return []string{
+		DefaultRelation,
+		"starred",
+		"owns",
+		"contributes",
+		"forkOf",
+		"hasTopic",
+		"isLang",
+	}
+}
+
+// ResourceCount is the number of entities of a given resource.
+type ResourceCount struct {
+	XID     string
+	Name    string
+	Group   string
+	Version string
+	Kind    string
+	Count   int
+}
+
+// Degree is entity degree.
+type Degree struct {
+	XID       string
+	Name      string
+	Namespace string
+	Resource  string
+	Degree    int
+}
+
+// Stats are graph statistics.
+type Stats struct {
+	// Entities is the number of entities
+	Entities int
+	// Resources is the number of resources
+	Resources int
+	// Links is the number of links
+	Links int
+	// ByResource counts entities per resource
+	ByResource []ResourceCount
+	// ByKind counts entities per resource kind
+	ByKind map[string]int
+	// ByNamespace counts entities per namespace
+	ByNamespace map[string]int
+	// ByRelation counts links per relation; see WithRelations
+	ByRelation map[string]int
+	// InDegree maps the lower bound of power of two in-degree buckets
+	// to the number of entities whose in-degree falls into the bucket
+	InDegree map[int]int
+	// OutDegree maps the lower bound of power of two out-degree buckets
+	// to the number of entities whose out-degree falls into the bucket
+	OutDegree map[int]int
+	// TopIn are entities with the highest in-degree
+	TopIn []Degree
+	// TopOut are entities with the highest out-degree
+	TopOut []Degree
+}
+
+// StatsOptions configure Stats.
+type StatsOptions struct {
+	TopN      int
+	Relations []string
+}
+
+// StatsOption is Stats option.
+type StatsOption func(*StatsOptions)
+
+// WithTopN sets the number of top entities returned in Stats.
+func WithTopN(n int) StatsOption {
+	return func(o *StatsOptions) {
+		o.TopN = n
+	}
+}
+
+// WithRelations sets the relations whose links are counted in Stats.
+// NOTE: relations are stored as link facets which dgraph can't group by,
+// so only the links of the given relations are counted.
+// The links of DefaultRelations are counted if no relations are given.
+func WithRelations(r ...string) StatsOption {
+	return func(o *StatsOptions) {
+		o.Relations = append(o.Relations, r...)
+	}
+}
+
+// Stats returns graph statistics.
+// The counts, degree distributions and top entities are all aggregated by dgraph,
+// so Stats does not need to load the entities, their links nor attributes.
+// Links are counted per relation for DefaultRelations unless WithRelations is given.
+// NOTE: the maximum degrees are queried first, so the degree distributions
+// are only counted in the buckets which can contain any entities.
+func (s *Store) Stats(ctx context.Context, opts ...StatsOption) (*Stats, error) {
+	sopts := StatsOptions{}
+	for _, apply := range opts {
+		apply(&sopts)
+	}
+
+	if sopts.TopN <= 0 {
+		sopts.TopN = DefaultTopN
+	}
+
+	if len(sopts.Relations) == 0 {
+		sopts.Relations = DefaultRelations()
+	}
+
+	maxIn, maxOut, err := s.maxDegrees(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := s.statsRequest(ctx, sopts, maxIn, maxOut)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.do(ctx, QueryOp, req)
+	if err != nil {
+		return nil, fmt.Errorf("txn.Stats: %w", err)
+	}
+
+	return decodeJSONStats(resp.Json, sopts.Relations)
+}
+
+// maxDegrees returns the maximum in and out degree of entities.
+func (s *Store) maxDegrees(ctx context.Context) (int, int, error) {
+	req := &dgapi.Request{
+		Query: `
+	{
+		var(func: type(Entity)) {
+			indeg as count(~links)
+			outdeg as count(links)
+		}
+
+		max() {
+			in: max(val(indeg))
+			out: max(val(outdeg))
+		}
+	}
+	`,
+		ReadOnly: true,
+	}
+
+	resp, err := s.do(ctx, QueryOp, req)
+	if err != nil {
+		return 0, 0, fmt.Errorf("txn.Stats: %w", err)
+	}
+
+	var result struct {
+		Max []map[string]float64 `json:"max"`
+	}
+
+	if err := json.Unmarshal(resp.Json, &result); err != nil {
+		return 0, 0, fmt.Errorf("decode max degrees: %w", err)
+	}
+
+	var maxIn, maxOut int
+
+	// NOTE: each aggregate is returned in a separate object
+	for _, m := range result.Max {
+		if v, ok := m["in"]; ok {
+			maxIn = int(v)
+		}
+		if v, ok := m["out"]; ok {
+			maxOut = int(v)
+		}
+	}
+
+	return maxIn, maxOut, nil
+}
+
+// statsRequest creates a dgraph API request for querying graph statistics and returns it.
+// Degree distributions are counted in power of two buckets: [0,1), [1,2), [2,4), ...
+// up to the bucket of the maximum in and out degree, respectively.
+// The top n entities by in and out degree are returned along with the aggregated counts.
+func (s *Store) statsRequest(ctx context.Context, opts StatsOptions, maxIn, maxOut int) (*dgapi.Request, error) {
+	n := strconv.Itoa(opts.TopN)
+
+	var q strings.Builder
+
+	q.WriteString(`
+	{
+		var(func: type(Entity)) {
+			indeg as count(~links)
+			outdeg as count(links)
+`)
+
+	for i, r := range opts.Relations {
+		fmt.Fprintf(&q, "\t\t\trel%d as count(links @facets(eq(relation, %s)))\n", i, strconv.Quote(r))
+	}
+
+	q.WriteString(`		}
+
+		total(func: type(Entity)) {
+			count(uid)
+		}
+
+		sums() {
+			links: sum(val(outdeg))
+`)
+
+	for i := range opts.Relations {
+		fmt.Fprintf(&q, "\t\t\trel%d: sum(val(rel%d))\n", i, i)
+	}
+
+	q.WriteString(`		}
+
+		resources(func: type(Resource)) {
+			xid
+			name
+			group
+			version
+			kind
+			count: count(~resource)
+		}
+
+		namespaces(func: type(Entity)) @groupby(namespace) {
+			count(uid)
+		}
+`)
+
+	for _, d := range []struct {
+		v   string
+		max int
+	}{
+		{"indeg", maxIn},
+		{"outdeg", maxOut},
+	} {
+		buckets := degreeBuckets(d.max)
+
+		for i, lo := range buckets {
+			filter := fmt.Sprintf("ge(val(%s), %d)", d.v, lo)
+			if i+1 < len(buckets) {
+				filter += fmt.Sprintf(" AND lt(val(%s), %d)", d.v, buckets[i+1])
+			}
+
+			fmt.Fprintf(&q, "\n\t\t%s%d(func: uid(%s)) @filter(%s) {\n\t\t\tcount(uid)\n\t\t}\n", d.v, lo, d.v, filter)
+		}
+	}
+
+	q.WriteString(`
+		topIn(func: uid(indeg), orderdesc: val(indeg), first: ` + n + `) @filter(gt(val(indeg), 0)) {
+			xid
+			name
+			namespace
+			resource {
+				name
+			}
+			degree: val(indeg)
+		}
+
+		topOut(func: uid(outdeg), orderdesc: val(outdeg), first: ` + n + `) @filter(gt(val(outdeg), 0)) {
+			xid
+			name
+			namespace
+			resource {
+				name
+			}
+			degree: val(outdeg)
+		}
+	}
+	`)
+
+	return &dgapi.Request{
+		Query:    q.String(),
+		ReadOnly: true,
+	}, nil
+}
+
+// degreeBuckets returns the lower bounds of degree distribution buckets up to the bucket of max degree.
+// The last bucket is unbounded.
+func degreeBuckets(max int) []int {
+	buckets := []int{0}
+	for lo := 1; lo <= max; lo <<= 1 {
+		buckets = append(buckets, lo)
+	}
+
+	return buckets
+}
+
+type degreeJSON struct {
+	XID       string    `json:"xid"`
+	Name      string    `json:"name"`
+	Namespace string    `json:"namespace"`
+	Resource  *Resource `json:"resource"`
+	Degree    int       `json:"degree"`
+}
+
+func (d degreeJSON) toDegree() Degree {
+	deg := Degree{
+		XID:       d.XID,
+		Name:      d.Name,
+		Namespace: d.Namespace,
+		Degree:    d.Degree,
+	}
+
+	if d.Resource != nil {
+		deg.Resource = d.Resource.Name
+	}
+
+	return deg
+}
+
+// decodeJSONStats decodes stats query JSON response and returns Stats.
+// Links of the given relations are counted in the same order as they were queried.
+func decodeJSONStats(b []byte, relations []string) (*Stats, error) {
+	var result struct {
+		Total []struct {
+			Count int `json:"count"`
+		} `json:"total"`
+		Sums      []map[string]float64 `json:"sums"`
+		Resources []struct {
+			Resource
+			Count int `json:"count"`
+		} `json:"resources"`
+		Namespaces []struct {
+			GroupBy []struct {
+				Namespace string `json:"namespace"`
+				Count     int    `json:"count"`
+			} `json:"@groupby"`
+		} `json:"namespaces"`
+		TopIn  []degreeJSON `json:"topIn"`
+		TopOut []degreeJSON `json:"topOut"`
+	}
+
+	if err := json.Unmarshal(b, &result); err != nil {
+		return nil, fmt.Errorf("decodeJSONStats: %w", err)
+	}
+
+	// NOTE: degree buckets are returned in blocks named after their lower bounds
+	var buckets map[string][]struct {
+		Count int `json:"count"`
+	}
+
+	if err := json.Unmarshal(b, &buckets); err != nil {
+		return nil, fmt.Errorf("decodeJSONStats: %w", err)
+	}
+
+	stats := &Stats{
+		Resources:   len(result.Resources),
+		ByResource:  make([]ResourceCount, 0, len(result.Resources)),
+		ByKind:      make(map[string]int),
+		ByNamespace: make(map[string]int),
+		ByRelation:  make(map[string]int),
+		InDegree:    make(map[int]int),
+		OutDegree:   make(map[int]int),
+		TopIn:       make([]Degree, 0, len(result.TopIn)),
+		TopOut:      make([]Degree, 0, len(result.TopOut)),
+	}
+
+	if len(result.Total) > 0 {
+		stats.Entities = result.Total[0].Count
+	}
+
+	// NOTE: each aggregate is returned in a separate object
+	for _, sum := range result.Sums {
+		if v, ok := sum["links"]; ok {
+			stats.Links = int(v)
+		}
+
+		for i, r := range relations {
+			if v, ok := sum["rel"+strconv.Itoa(i)]; ok {
+				stats.ByRelation[r] += int(v)
+			}
+		}
+	}
+
+	for _, r := range result.Resources {
+		stats.ByResource = append(stats.ByResource, ResourceCount{
+			XID:     r.XID,
+			Name:    r.Name,
+			Group:   r.Group,
+			Version: r.Version,
+			Kind:    r.Kind,
+			Count:   r.Count,
+		})
+		stats.ByKind[r.Kind] += r.Count
+	}
+
+	sort.SliceStable(stats.ByResource, func(i, j int) bool {
+		return stats.ByResource[i].Count > stats.ByResource[j].Count
+	})
+
+	for _, ns := range result.Namespaces {
+		for _, g := range ns.GroupBy {
+			stats.ByNamespace[g.Namespace] += g.Count
+		}
+	}
+
+	for name, b := range buckets {
+		for v, dist := range map[string]map[int]int{"indeg": stats.InDegree, "outdeg": stats.OutDegree} {
+			if !strings.HasPrefix(name, v) {
+				continue
+			}
+
+			lo, err := strconv.Atoi(strings.TrimPrefix(name, v))
+			if err != nil {
+				continue
+			}
+
+			if len(b) > 0 && b[0].Count > 0 {
+				dist[lo] = b[0].Count
+			}
+		}
+	}
+
+	for _, d := range result.TopIn {
+		stats.TopIn = append(stats.TopIn, d.toDegree())
+	}
+
+	for _, d := range result.TopOut {
+		stats.TopOut = append(stats.TopOut, d.toDegree())
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,124 @@
+package dgraph
+
+import (
+	"context"
+	"io/ioutil"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodeJSONStats(t *testing.T) {
+	data, err := ioutil.ReadFile(path.Join(testDir, "stats.json"))
+	if err != nil {
+		t.Fatalf("failed opening file: %v", err)
+	}
+
+	stats, err := decodeJSONStats(data, []string{"owns", DefaultRelation})
+	if err != nil {
+		t.Fatalf("failed decoding data: %v", err)
+	}
+
+	if stats.Entities != 3 {
+		t.Errorf("expected entities: %d, got: %d", 3, stats.Entities)
+	}
+
+	if stats.Links != 2 {
+		t.Errorf("expected links: %d, got: %d", 2, stats.Links)
+	}
+
+	if c := stats.ByKind[resKind]; c != 3 {
+		t.Errorf("expected %s kind count: %d, got: %d", resKind, 3, c)
+	}
+
+	if c := stats.ByNamespace["entNs"]; c != 2 {
+		t.Errorf("expected namespace count: %d, got: %d", 2, c)
+	}
+
+	if c := stats.ByRelation["owns"]; c != 1 {
+		t.Errorf("expected relation count: %d, got: %d", 1, c)
+	}
+
+	if c := stats.ByRelation[DefaultRelation]; c != 1 {
+		t.Errorf("expected default relation count: %d, got: %d", 1, c)
+	}
+
+	if c := stats.InDegree[1]; c != 2 {
+		t.Errorf("expected in-degree count: %d, got: %d", 2, c)
+	}
+
+	if _, ok := stats.InDegree[2]; ok {
+		t.Errorf("unexpected empty in-degree bucket")
+	}
+
+	if c := stats.OutDegree[2]; c != 1 {
+		t.Errorf("expected out-degree count: %d, got: %d", 1, c)
+	}
+
+	if len(stats.TopOut) != 1 || stats.TopOut[0].Degree != 2 || stats.TopOut[0].Resource != resName {
+		t.Errorf("unexpected top out-degree entities: %v", stats.TopOut)
+	}
+}
+
+func TestStatsRequest(t *testing.T) {
+	s := &Store{}
+
+	req, err := s.statsRequest(context.Background(), StatsOptions{TopN: 3, Relations: []string{"owns"}}, 5, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// NOTE: entity links must be aggregated by dgraph rather than fetched
+	for _, want := range []string{
+		`rel0 as count(links @facets(eq(relation, "owns")))`,
+		"rel0: sum(val(rel0))",
+		"links: sum(val(outdeg))",
+		"indeg0(func: uid(indeg)) @filter(ge(val(indeg), 0) AND lt(val(indeg), 1))",
+		"outdeg4(func: uid(outdeg)) @filter(ge(val(outdeg), 4) AND lt(val(outdeg), 8))",
+		"first: 3",
+	} {
+		if !strings.Contains(req.Query, want) {
+			t.Errorf("expected query to contain: %s", want)
+		}
+	}
+
+	if strings.Contains(req.Query, "@facets(relation)") {
+		t.Errorf("unexpected link facets traversal")
+	}
+
+	// NOTE: buckets are only emitted up to the bucket of max degree
+	for _, want := range []string{
+		"indeg4(func: uid(indeg)) @filter(ge(val(indeg), 4))",
+		"outdeg8(func: uid(outdeg)) @filter(ge(val(outdeg), 8))",
+	} {
+		if !strings.Contains(req.Query, want) {
+			t.Errorf("expected unbounded last bucket: %s", want)
+		}
+	}
+
+	for _, unwanted := range []string{"indeg8(", "outdeg16("} {
+		if strings.Contains(req.Query, unwanted) {
+			t.Errorf("unexpected bucket beyond max degree: %s", unwanted)
+		}
+	}
+}
+
+func TestDegreeBuckets(t *testing.T) {
+	testCases := []struct {
+		max  int
+		want []int
+	}{
+		{0, []int{0}},
+		{1, []int{0, 1}},
+		{3, []int{0, 1, 2}},
+		{4, []int{0, 1, 2, 4}},
+		{100, []int{0, 1, 2, 4, 8, 16, 32, 64}},
+	}
+
+	for _, tc := range testCases {
+		if got := degreeBuckets(tc.max); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("max %d: expected buckets: %v, got: %v", tc.max, tc.want, got)
+		}
+	}
+}
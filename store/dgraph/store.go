@@ -14,7 +14,12 @@ import (
 
 // Store is dgraph store
 type Store struct {
-	c *Client
+	c    *Client
+	opts Options
+	// cache caches dgraph uids of xids
+	cache *uidCache
+	// snap is read-only snapshot
+	snap *snapshot
 }
 
 // New creates new dgraph store and returns it.
@@ -29,47 +34,162 @@ func NewStore(dsn string, opts ...Option) (*Store, error) {
 		return nil, err
 	}
 
+	// NOTE: dry-run requests must not be silently discarded
+	if sopts.DryRun && sopts.Recorder == nil {
+		sopts.Recorder = NewLogRecorder(nil)
+	}
+
+	var cache *uidCache
+	if sopts.UIDCacheSize > 0 {
+		cache = newUIDCache(sopts.UIDCacheSize)
+	}
+
 	return &Store{
-		c: c,
+		c:     c,
+		opts:  sopts,
+		cache: cache,
 	}, nil
 }
 
+// do executes req of the given op in a new transaction.
+// Every request is recorded by the store Recorder before it's executed.
+// In dry-run mode requests which contain mutations are not executed
+// and an empty response is returned instead.
+// Read-only requests of snapshot stores are executed in the snapshot transaction.
+// The operation options opts override the store best-effort and timeout options.
+func (s *Store) do(ctx context.Context, op Op, req *dgapi.Request, opts ...store.Option) (*dgapi.Response, error) {
+	return s.exec(ctx, op, req, req, opts...)
+}
+
+// doCached executes req of the given op built with uid cache.
+// Cached nodes are referenced by their dgraph uids which are only valid
+// in this dgraph database, so the store Recorder records the request
+// rebuilt by build without uid cache, which can be replayed anywhere.
+func (s *Store) doCached(ctx context.Context, op Op, req *dgapi.Request, build func(*Store) (*dgapi.Request, error), opts ...store.Option) (*dgapi.Response, error) {
+	rec := req
+
+	if s.cache != nil && s.opts.Recorder != nil {
+		var err error
+		if rec, err = build(s.uncached()); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.exec(ctx, op, req, rec, opts...)
+}
+
+// uncached returns a copy of s without uid cache.
+func (s *Store) uncached() *Store {
+	u := *s
+	u.cache = nil
+
+	return &u
+}
+
+// exec records rec and executes req of the given op.
+// rec is req which references nodes by their xids rather than cached dgraph uids.
+// Read-only requests with WithReadTs option are executed at the given start timestamp.
+func (s *Store) exec(ctx context.Context, op Op, req, rec *dgapi.Request, opts ...store.Option) (*dgapi.Response, error) {
+	if s.snap != nil && !req.ReadOnly {
+		return nil, ErrSnapshotReadOnly
+	}
+
+	oopts := newOpOptions(s.opts, opts...)
+
+	if req.ReadOnly && s.snap == nil {
+		req.BestEffort = oopts.BestEffort
+		rec.BestEffort = oopts.BestEffort
+		req.StartTs = oopts.ReadTs
+		rec.StartTs = oopts.ReadTs
+	}
+
+	if s.opts.Recorder != nil {
+		if err := s.opts.Recorder.Record(op, rec); err != nil {
+			return nil, fmt.Errorf("record %v: %w", op, err)
+		}
+	}
+
+	if s.opts.DryRun && len(req.Mutations) > 0 {
+		return &dgapi.Response{}, nil
+	}
+
+	if oopts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, oopts.Timeout)
+		defer cancel()
+	}
+
+	if s.snap != nil {
+		return s.snap.do(ctx, req)
+	}
+
+	if req.ReadOnly && req.StartTs > 0 {
+		return s.c.queryAt(ctx, req)
+	}
+
+	if req.ReadOnly {
+		return s.c.NewReadOnlyTxn().Do(ctx, req)
+	}
+
+	return s.c.NewTxn().Do(ctx, req)
+}
+
 // Alter alters dgraph database with the given operation.
 func (s *Store) Alter(ctx context.Context, op *dgapi.Operation) error {
 	return s.c.Alter(ctx, op)
 }
 
 // Close closes store.
+// Closing snapshot store discards the snapshot transaction but keeps the connection open.
 func (s *Store) Close() error {
+	if s.snap != nil {
+		return s.snap.close()
+	}
+
 	return s.c.Close()
 }
 
 // Add Entity to store.
 func (s *Store) Add(ctx context.Context, e store.Entity, opts ...store.Option) error {
+	epoch := s.cache.Epoch()
+
 	req, err := s.addRequest(ctx, e, opts...)
 	if err != nil {
 		return err
 	}
 
-	if _, err := s.c.NewTxn().Do(ctx, req); err != nil {
+	resp, err := s.doCached(ctx, AddOp, req, func(s *Store) (*dgapi.Request, error) {
+		return s.addRequest(ctx, e, opts...)
+	}, opts...)
+	if err != nil {
 		return fmt.Errorf("txn.Add: %w", err)
 	}
 
+	s.cache.putUIDs(epoch, resp.Uids, addVars(e))
+	s.cache.putJSON(epoch, resp.Json)
+
 	return nil
 }
 
 // Get Entity from store.
 func (s *Store) Get(ctx context.Context, uid uuid.UID, opts ...store.Option) (store.Entity, error) {
+	epoch := s.cache.Epoch()
+
 	req, err := s.getRequest(ctx, uid)
 	if err != nil {
 		return nil, err
 	}
 
-	resp, err := s.c.NewTxn().Do(ctx, req)
+	resp, err := s.do(ctx, GetOp, req, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("txn.Get: %w", err)
 	}
 
+	// NOTE: uids read at a past timestamp may be stale
+	if req.StartTs == 0 {
+		s.cache.putJSON(epoch, resp.Json)
+	}
+
 	ents, err := decodeJSONEntity(resp.Json, GetOp)
 	if err != nil {
 		return nil, err
@@ -88,12 +208,17 @@ func (s *Store) Get(ctx context.Context, uid uuid.UID, opts ...store.Option) (st
 
 // Delete Entity from store.
 func (s *Store) Delete(ctx context.Context, uid uuid.UID, opts ...store.Option) error {
+	// NOTE: uid is removed from cache again once the node is deleted,
+	// so it's not cached by the requests which read it before the deletion.
+	s.cache.Remove(uid.Value())
+	defer s.cache.Remove(uid.Value())
+
 	req, err := s.deleteRequest(ctx, uid)
 	if err != nil {
 		return err
 	}
 
-	if _, err := s.c.NewTxn().Do(ctx, req); err != nil {
+	if _, err := s.do(ctx, DelOp, req, opts...); err != nil {
 		return fmt.Errorf("txn.Delete: %w", err)
 	}
 
@@ -107,7 +232,11 @@ func (s *Store) Link(ctx context.Context, from, to uuid.UID, opts ...store.Optio
 		return err
 	}
 
-	if _, err := s.c.NewTxn().Do(ctx, req); err != nil {
+	build := func(s *Store) (*dgapi.Request, error) {
+		return s.linkRequest(ctx, from, to, opts...)
+	}
+
+	if _, err := s.doCached(ctx, LinkOp, req, build, opts...); err != nil {
 		return fmt.Errorf("txn.Link: %w", err)
 	}
 
@@ -121,7 +250,7 @@ func (s *Store) Unlink(ctx context.Context, from, to uuid.UID, opts ...store.Opt
 		return err
 	}
 
-	if _, err := s.c.NewTxn().Do(ctx, req); err != nil {
+	if _, err := s.do(ctx, UnlinkOp, req, opts...); err != nil {
 		return fmt.Errorf("txn.Unlink: %w", err)
 	}
 
@@ -1,12 +1,17 @@
 package dgraph
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"flag"
 	"reflect"
+	"strconv"
+	"strings"
 	"testing"
 
 	dgapi "github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/milosgajdos/netscrape/pkg/entity"
 	"github.com/milosgajdos/netscrape/pkg/space"
 	"github.com/milosgajdos/netscrape/pkg/store"
 	"github.com/milosgajdos/netscrape/pkg/uuid"
@@ -295,3 +300,438 @@ func TestUnlink(t *testing.T) {
 		}
 	})
 }
+
+func TestStats(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode.")
+	}
+
+	s := MustNewStore(*host, *drop, t)
+	defer s.Close()
+
+	obj1, err := newTestEntity("ent1", "entNs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Add(context.Background(), obj1); err != nil {
+		t.Fatal(err)
+	}
+
+	obj2, err := newTestEntity("ent2", "entNs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Add(context.Background(), obj2); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Link(context.Background(), obj1.UID(), obj2.UID()); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := s.Stats(context.Background(), WithTopN(1), WithRelations(DefaultRelation))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c := stats.ByRelation[DefaultRelation]; c < 1 {
+		t.Errorf("expected at least %d %s links, got: %d", 1, DefaultRelation, c)
+	}
+
+	if c := stats.InDegree[1]; c < 1 {
+		t.Errorf("expected at least %d entities with in-degree 1, got: %d", 1, c)
+	}
+
+	if stats.Entities < 2 {
+		t.Errorf("expected at least %d entities, got: %d", 2, stats.Entities)
+	}
+
+	if stats.Links < 1 {
+		t.Errorf("expected at least %d links, got: %d", 1, stats.Links)
+	}
+
+	if len(stats.TopIn) != 1 {
+		t.Errorf("expected %d top in-degree entities, got: %d", 1, len(stats.TopIn))
+	}
+}
+
+func TestBuffer(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode.")
+	}
+
+	s := MustNewStore(*host, *drop, t)
+	defer s.Close()
+
+	var errs []error
+
+	b, err := NewBuffer(s, WithErrorHandler(func(err error) { errs = append(errs, err) }))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	obj1, err := newTestEntity("ent1", "entNs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	obj2, err := newTestEntity("ent2", "entNs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, obj := range []space.Entity{obj1, obj2} {
+		if err := b.Add(context.Background(), obj); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := b.Link(context.Background(), obj1.UID(), obj2.UID()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(errs) != 0 {
+		t.Fatalf("unexpected flush errors: %v", errs)
+	}
+
+	if _, err := s.Get(context.Background(), obj2.UID()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUIDCacheStore(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode.")
+	}
+
+	s := MustNewStore(*host, *drop, t)
+	defer s.Close()
+
+	s.cache = newUIDCache(10)
+
+	obj, err := newTestEntity("ent1", "entNs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Add(context.Background(), obj); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Get(context.Background(), obj.UID()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := s.cache.Get(obj.UID().Value()); !ok {
+		t.Fatalf("expected %s to be cached", obj.UID().Value())
+	}
+
+	if err := s.Delete(context.Background(), obj.UID()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := s.cache.Get(obj.UID().Value()); ok {
+		t.Fatalf("expected %s to be removed from cache", obj.UID().Value())
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode.")
+	}
+
+	s := MustNewStore(*host, true, t)
+	defer s.Close()
+
+	snap, err := s.Snapshot(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer snap.Close()
+
+	if snap.StartTs() == 0 {
+		t.Fatalf("expected non-zero snapshot start timestamp")
+	}
+
+	obj, err := newTestEntity("ent1", "entNs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Add(context.Background(), obj); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := snap.Get(context.Background(), obj.UID()); err != store.ErrEntityNotFound {
+		t.Fatalf("got: %v, want: %v", err, store.ErrEntityNotFound)
+	}
+
+	if err := snap.Add(context.Background(), obj); !errors.Is(err, ErrSnapshotReadOnly) {
+		t.Fatalf("got: %v, want: %v", err, ErrSnapshotReadOnly)
+	}
+}
+
+func TestBackupRestore(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode.")
+	}
+
+	s := MustNewStore(*host, true, t)
+	defer s.Close()
+
+	obj1, err := newTestEntity("ent1", "entNs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	obj2, err := newTestEntity("ent2", "entNs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, obj := range []space.Entity{obj1, obj2} {
+		if err := s.Add(context.Background(), obj); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := s.Link(context.Background(), obj1.UID(), obj2.UID()); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+
+	if err := s.Backup(context.Background(), &buf, WithPageSize(1)); err != nil {
+		t.Fatal(err)
+	}
+
+	s = MustNewStore(*host, true, t)
+	defer s.Close()
+
+	if err := s.Restore(context.Background(), bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+
+	e, err := s.Get(context.Background(), obj1.UID())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(obj1, e.(space.Entity)) {
+		t.Fatalf("expected: %v, got: %v", obj1, e.(space.Entity))
+	}
+
+	err = s.Restore(context.Background(), bytes.NewReader(buf.Bytes()), WithConflict(Fail))
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("got: %v, want: %v", err, ErrConflict)
+	}
+}
+
+func TestFind(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode.")
+	}
+
+	types := map[string]AttrType{"stars": IntAttr}
+
+	s := MustNewStore(*host, true, t)
+	defer s.Close()
+
+	s.opts.AttrTypes = types
+
+	if err := s.Alter(context.Background(), &dgapi.Operation{Schema: AttrSchema(types)}); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, name := range []string{"ent1", "ent2", "ent3"} {
+		obj, err := newTestEntity(name, "entNs")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		obj.Attrs().Set("stars", strconv.Itoa(i*100))
+
+		if err := s.Add(context.Background(), obj); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ents, err := s.Find(context.Background(), WithFilters(Ge("stars", "100")), WithOrder("stars", true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ents) != 2 {
+		t.Fatalf("expected entities: %d, got: %d", 2, len(ents))
+	}
+
+	if stars := ents[0].Attrs().Get("stars"); stars != "200" {
+		t.Errorf("expected stars: %s, got: %s", "200", stars)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode.")
+	}
+
+	t.Run("OK", func(t *testing.T) {
+		s := MustNewStore(*host, *drop, t)
+		defer s.Close()
+
+		obj, err := newTestEntity("ent1", "entNs")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := s.Add(context.Background(), obj); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := s.Update(context.Background(), obj.UID(), Patch{Namespace: "entNs2"}); err != nil {
+			t.Fatal(err)
+		}
+
+		e, err := s.Get(context.Background(), obj.UID())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if ns := e.(space.Entity).Namespace(); ns != "entNs2" {
+			t.Errorf("expected namespace: %s, got: %s", "entNs2", ns)
+		}
+	})
+
+	t.Run("ErrEntityNotFound", func(t *testing.T) {
+		s := MustNewStore(*host, *drop, t)
+		defer s.Close()
+
+		uid, err := uuid.New()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := s.Update(context.Background(), uid, Patch{Name: "foo"}); err != store.ErrEntityNotFound {
+			t.Fatalf("got: %v, want: %v", err, store.ErrEntityNotFound)
+		}
+	})
+}
+
+func TestList(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode.")
+	}
+
+	s := MustNewStore(*host, true, t)
+	defer s.Close()
+
+	for _, name := range []string{"ent1", "ent2", "ent3"} {
+		obj, err := newTestEntity(name, "entNs")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := s.Add(context.Background(), obj); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var names []string
+
+	res := &ListResult{}
+	for {
+		var err error
+		res, err = s.List(context.Background(), WithSort(OrderByName, true), WithLimit(2), WithCursor(res.Cursor))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, e := range res.Entities {
+			names = append(names, e.(space.Entity).Name())
+		}
+
+		if res.Cursor == "" {
+			break
+		}
+	}
+
+	if got, want := strings.Join(names, ","), "ent3,ent2,ent1"; got != want {
+		t.Errorf("expected entities: %s, got: %s", want, got)
+	}
+
+	res, err := s.List(context.Background(), WithType(entity.ResourceType), WithKind(resKind))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(res.Entities) != 1 {
+		t.Errorf("expected resources: %d, got: %d", 1, len(res.Entities))
+	}
+}
+
+func TestTopRoundTrip(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode.")
+	}
+
+	s := MustNewStore(*host, true, t)
+	defer s.Close()
+
+	tp, err := newTestTop()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ents, err := tp.Entities(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// NOTE: attributes named after dgraph predicates, e.g. created_at or type, would not be listed
+	for _, e := range ents {
+		e.Attrs().Set("repo_created_at", "2021-03-06T11:39:40Z")
+		e.Attrs().Set("owner_type", "Organization")
+
+		if err := s.Add(context.Background(), e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	listed := make(map[string]space.Entity)
+
+	res := &ListResult{}
+	for {
+		res, err = s.List(context.Background(), WithNamespace("entNs"), WithCursor(res.Cursor))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, e := range res.Entities {
+			listed[e.UID().Value()] = e.(space.Entity)
+		}
+
+		if res.Cursor == "" {
+			break
+		}
+	}
+
+	for _, e := range ents {
+		got, ok := listed[e.UID().Value()]
+		if !ok {
+			t.Errorf("entity %s not listed", e.Name())
+			continue
+		}
+
+		for _, k := range e.Attrs().Keys() {
+			if v := got.Attrs().Get(k); v != e.Attrs().Get(k) {
+				t.Errorf("entity %s attribute %s: expected: %q, got: %q", e.Name(), k, e.Attrs().Get(k), v)
+			}
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package dgraph
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	dgapi "github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/milosgajdos/netscrape/pkg/space"
+	"github.com/milosgajdos/netscrape/pkg/store"
+	"github.com/milosgajdos/netscrape/pkg/uuid"
+)
+
+// Patch is a partial entity update.
+// Zero value fields are left unchanged.
+type Patch struct {
+	// Set sets the given attributes
+	Set map[string]string
+	// Remove removes the given attributes
+	Remove []string
+	// Name renames entity
+	Name string
+	// Namespace moves entity to namespace
+	Namespace string
+	// Resource re-points entity to resource
+	Resource space.Resource
+}
+
+// Update updates entity with the given uid with patch p.
+// Update is executed as a conditional upsert, so nothing is changed
+// and store.ErrEntityNotFound is returned if the entity does not exist.
+// Attributes which are both set and removed by p are removed.
+// If p re-points the entity to a resource which does not exist, the resource is created.
+func (s *Store) Update(ctx context.Context, uid uuid.UID, p Patch, opts ...store.Option) error {
+	req, err := s.updateRequest(ctx, uid, p)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.do(ctx, UpdateOp, req, opts...)
+	if err != nil {
+		return fmt.Errorf("txn.Update: %w", err)
+	}
+
+	// NOTE: dry-run responses are empty
+	if len(resp.Json) == 0 {
+		return nil
+	}
+
+	var result struct {
+		Entity []struct {
+			XID string `json:"xid"`
+		} `json:"entity"`
+	}
+
+	if err := json.Unmarshal(resp.Json, &result); err != nil {
+		return fmt.Errorf("decode update: %w", err)
+	}
+
+	if len(result.Entity) == 0 {
+		return store.ErrEntityNotFound
+	}
+
+	return nil
+}
+
+// updateRequest creates a dgraph API request for patching entity with the given uid and returns it.
+// The entity is updated only if it exists and is of Entity type.
+func (s *Store) updateRequest(ctx context.Context, uid uuid.UID, p Patch) (*dgapi.Request, error) {
+	q := `
+	{
+		entity(func: eq(xid, "` + uid.Value() + `")) @filter(type(Entity)) {
+			xid
+			e as uid
+			attrs {
+				a as uid
+			}
+		}
+	`
+
+	if p.Resource != nil {
+		q += `
+		resource(func: eq(xid, "` + p.Resource.UID().Value() + `")) {
+			r as uid
+		}
+	`
+	}
+
+	q += `}`
+
+	remove := make(map[string]bool)
+	for _, k := range p.Remove {
+		remove[k] = true
+	}
+
+	set := map[string]interface{}{
+		"uid": "uid(e)",
+	}
+
+	if p.Name != "" {
+		set["name"] = p.Name
+	}
+
+	if p.Namespace != "" {
+		set["namespace"] = p.Namespace
+	}
+
+	if p.Resource != nil {
+		set["resource"] = resourceNode(p.Resource, "uid(r)", s.opts.AttrTypes)
+	}
+
+	attrs := Attrs{"uid": "uid(a)"}
+	for k, v := range p.Set {
+		if !remove[k] {
+			attrs[k] = typedValue(v, s.opts.AttrTypes[k])
+		}
+	}
+
+	if len(attrs) > 1 {
+		set["attrs"] = attrs
+	}
+
+	var mutations []*dgapi.Mutation
+
+	if len(set) > 1 {
+		mu, err := MutationJSON(AddOp, set, `@if(gt(len(e), 0))`)
+		if err != nil {
+			return nil, err
+		}
+		mutations = append(mutations, mu)
+	}
+
+	if len(remove) > 0 {
+		del := map[string]interface{}{
+			"uid": "uid(a)",
+		}
+		for k := range remove {
+			del[k] = nil
+		}
+
+		mu, err := MutationJSON(DelOp, del, `@if(gt(len(e), 0) AND gt(len(a), 0))`)
+		if err != nil {
+			return nil, err
+		}
+		mutations = append(mutations, mu)
+	}
+
+	// NOTE: created_at is set only if the resource is created by the update
+	if p.Resource != nil {
+		node := map[string]string{"uid": "uid(r)", "created_at": time.Now().UTC().Format(time.RFC3339)}
+
+		mu, err := MutationJSON(AddOp, node, `@if(gt(len(e), 0) AND eq(len(r), 0))`)
+		if err != nil {
+			return nil, err
+		}
+		mutations = append(mutations, mu)
+	}
+
+	return &dgapi.Request{
+		Query:     q,
+		Mutations: mutations,
+		CommitNow: true,
+	}, nil
+}
@@ -0,0 +1,96 @@
+package dgraph
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestUpdateRequest(t *testing.T) {
+	s := &Store{
+		opts: Options{
+			AttrTypes: map[string]AttrType{"stars": IntAttr},
+		},
+	}
+
+	obj, err := newTestEntity("ent1", "entNs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := newTestResource(resName, resGroup, resVersion, resKind, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := Patch{
+		Set:       map[string]string{"stars": "10", "foo": "bar"},
+		Remove:    []string{"foo", "baz"},
+		Name:      "ent2",
+		Namespace: "ns2",
+		Resource:  res,
+	}
+
+	req, err := s.updateRequest(context.Background(), obj.UID(), p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(req.Mutations) != 3 {
+		t.Fatalf("expected mutations: %d, got: %d", 3, len(req.Mutations))
+	}
+
+	var set struct {
+		Name     string                 `json:"name"`
+		Resource *Resource              `json:"resource"`
+		Attrs    map[string]interface{} `json:"attrs"`
+	}
+
+	if err := json.Unmarshal(req.Mutations[0].SetJson, &set); err != nil {
+		t.Fatal(err)
+	}
+
+	if set.Name != p.Name {
+		t.Errorf("expected name: %s, got: %s", p.Name, set.Name)
+	}
+
+	if set.Resource == nil || set.Resource.UID != "uid(r)" {
+		t.Errorf("expected resource uid: %s, got: %v", "uid(r)", set.Resource)
+	}
+
+	if _, ok := set.Attrs["foo"]; ok {
+		t.Errorf("expected removed attribute not to be set")
+	}
+
+	if _, ok := set.Attrs["stars"].(float64); !ok {
+		t.Errorf("expected numeric stars, got: %T", set.Attrs["stars"])
+	}
+
+	var del map[string]interface{}
+
+	if err := json.Unmarshal(req.Mutations[1].DeleteJson, &del); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, k := range p.Remove {
+		if v, ok := del[k]; !ok || v != nil {
+			t.Errorf("expected %s to be deleted", k)
+		}
+	}
+
+	created := req.Mutations[2]
+
+	if cond := `@if(gt(len(e), 0) AND eq(len(r), 0))`; created.Cond != cond {
+		t.Errorf("expected created_at condition: %s, got: %s", cond, created.Cond)
+	}
+
+	var createdAt map[string]string
+
+	if err := json.Unmarshal(created.SetJson, &createdAt); err != nil {
+		t.Fatal(err)
+	}
+
+	if createdAt["uid"] != "uid(r)" || createdAt["created_at"] == "" {
+		t.Errorf("expected resource created_at, got: %v", createdAt)
+	}
+}